@@ -0,0 +1,95 @@
+package gotel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// chatMessage is an alert or recovery laid out for the chat alerters, each renders it into its own payload
+type chatMessage struct {
+	Title    string
+	Text     string
+	Resolved bool
+	Fields   []chatField
+}
+
+type chatField struct {
+	Title string
+	Value string
+}
+
+// newChatMessage lays out the reservation's formatted alert message along with the fields everyone wants to see
+func newChatMessage(res reservation, resolved bool) chatMessage {
+	title := alertTitle(res)
+	if resolved {
+		title = "Job Recovered"
+	}
+	return chatMessage{
+		Title:    fmt.Sprintf("%s %s/%s", title, res.App, res.Component),
+		Text:     res.AlertMessage,
+		Resolved: resolved,
+		Fields: []chatField{
+			{Title: "App", Value: res.App},
+			{Title: "Component", Value: res.Component},
+			{Title: "Last Checkin", Value: res.formatAlert("{last}")},
+			{Title: "Since", Value: res.formatAlert("{since}")},
+			{Title: "Owner", Value: res.Owner},
+		},
+	}
+}
+
+// newChatDigest lists every failing reservation with how long ago it last checked in
+func newChatDigest(failures []reservation) chatMessage {
+	msg := chatMessage{
+		Title: fmt.Sprintf("%d jobs failing", len(failures)),
+		Text:  fmt.Sprintf("%d reservations are failing their checkins or runs", len(failures)),
+	}
+	for _, res := range failures {
+		msg.Fields = append(msg.Fields, chatField{
+			Title: res.App + "/" + res.Component,
+			Value: fmt.Sprintf("%s, last checkin %s. Owner %s", res.AlertType, res.formatAlert("{since}"), res.Owner),
+		})
+	}
+	return msg
+}
+
+// postChatDigests posts each recipient's digest, a channel or webhook url, returning the ones that took it
+func postChatDigests(digests []digest, post func(msg chatMessage, recipient string) bool) map[string]bool {
+	delivered := make(map[string]bool)
+	for _, d := range digests {
+		if post(newChatDigest(d.failures), d.recipient) {
+			delivered[d.recipient] = true
+		}
+	}
+	return delivered
+}
+
+// color is red for failures and green for recoveries, as hex without the #
+func (m chatMessage) color() string {
+	if m.Resolved {
+		return "2EB886"
+	}
+	return "D9534F"
+}
+
+// postChat sends a payload as JSON to a chat webhook
+func postChat(client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned [%s]", resp.Status)
+	}
+	return nil
+}
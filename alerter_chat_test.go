@@ -0,0 +1,96 @@
+package gotel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_newChatMessage(t *testing.T) {
+	res := reservation{App: "jimtest", Component: "monitor", Owner: "jim", AlertMessage: "failed checkin"}
+
+	msg := newChatMessage(res, false)
+	if msg.Title != "Job Failed to checkin jimtest/monitor" || msg.Text != "failed checkin" || msg.color() != "D9534F" {
+		t.Fatalf("Unexpected alert message [%+v]", msg)
+	}
+	res.AlertType = alertMaxRuntime
+	if msg = newChatMessage(res, false); msg.Title != "Job Over Max Runtime jimtest/monitor" {
+		t.Fatalf("Unexpected %s title [%s]", alertMaxRuntime, msg.Title)
+	}
+	msg = newChatMessage(res, true)
+	if msg.Title != "Job Recovered jimtest/monitor" || msg.color() != "2EB886" {
+		t.Fatalf("Unexpected recovery message [%+v]", msg)
+	}
+	if len(msg.Fields) != 5 || msg.Fields[4].Value != "jim" {
+		t.Fatalf("Unexpected fields [%+v]", msg.Fields)
+	}
+}
+
+func Test_newChatDigest(t *testing.T) {
+	failures := []reservation{
+		{App: "billing", Component: "import", Owner: "jim", AlertType: alertMissedCheckin},
+		{App: "search", Component: "index", Owner: "sue", AlertType: alertJobFailed},
+	}
+	msg := newChatDigest(failures)
+	if msg.Title != "2 jobs failing" || msg.Resolved {
+		t.Fatalf("Unexpected digest [%+v]", msg)
+	}
+	if len(msg.Fields) != 2 || msg.Fields[1].Title != "search/index" || !strings.Contains(msg.Fields[1].Value, "last checkin") {
+		t.Fatalf("Unexpected digest fields [%+v]", msg.Fields)
+	}
+}
+
+func Test_teamsAlert(t *testing.T) {
+	var card teamsCard
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&card); err != nil {
+			t.Errorf("Unable to decode Teams card [%v]", err)
+		}
+	}))
+	defer srv.Close()
+
+	teams := &teamsAlerter{}
+	teams.Cfg.Teams.WebhookURL = "http://unused.invalid"
+	teams.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", Owner: "jim", AlertMessage: "failed checkin"}
+	res.Alerters = []alerterRoute{{Name: "teams", Target: srv.URL}}
+	if !teams.Alert(res) {
+		t.Fatalf("Expected the Teams card to be sent to the routed webhook")
+	}
+	if card.Type != "MessageCard" || card.ThemeColor != "D9534F" || card.Text != "failed checkin" {
+		t.Fatalf("Unexpected card [%+v]", card)
+	}
+	if len(card.Sections) != 1 || len(card.Sections[0].Facts) != 5 {
+		t.Fatalf("Unexpected facts [%+v]", card.Sections)
+	}
+}
+
+func Test_mattermostAlert(t *testing.T) {
+	var msg slackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("Unable to decode Mattermost message [%v]", err)
+		}
+	}))
+	defer srv.Close()
+
+	mattermost := &mattermostAlerter{}
+	mattermost.Cfg.Mattermost.WebhookURL = srv.URL
+	mattermost.Cfg.Mattermost.Channel = "alerts"
+	mattermost.Cfg.Mattermost.IconURL = "http://example.com/gotel.png"
+	mattermost.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", AlertMessage: "recovered"}
+	if !mattermost.Resolve(res) {
+		t.Fatalf("Expected the Mattermost recovery to be sent")
+	}
+	if msg.Channel != "alerts" || msg.IconURL != "http://example.com/gotel.png" || msg.Text != "**Job Recovered jimtest/monitor**" {
+		t.Fatalf("Unexpected message [%+v]", msg)
+	}
+	if msg.Attachments[0].Color != "#2EB886" {
+		t.Fatalf("Unexpected color [%s]", msg.Attachments[0].Color)
+	}
+}
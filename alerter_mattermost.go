@@ -0,0 +1,68 @@
+package gotel
+
+import (
+	"net/http"
+	"time"
+)
+
+// mattermostAlerter posts to Mattermost compatible incoming webhooks, which take Slack's payload
+type mattermostAlerter struct {
+	Cfg    Config
+	client *http.Client
+}
+
+func (s *mattermostAlerter) Bootstrap() {
+	if s.Cfg.Mattermost.WebhookURL == "" {
+		panic("You have Mattermost alerting enabled but have not provided a webhookurl under [mattermost]")
+	}
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	l.info("alerter_mattermost default channel [%s]", s.Cfg.Mattermost.Channel)
+}
+
+func (s *mattermostAlerter) Name() string {
+	return "Mattermost"
+}
+
+func (s *mattermostAlerter) Alert(res reservation) bool {
+	return s.send(res, false)
+}
+
+func (s *mattermostAlerter) Resolve(res reservation) bool {
+	return s.send(res, true)
+}
+
+func (s *mattermostAlerter) Recipients(res reservation) []string {
+	return []string{s.channel(res)}
+}
+
+func (s *mattermostAlerter) Digest(digests []digest) map[string]bool {
+	return postChatDigests(digests, s.post)
+}
+
+// channel is the reservation's routed channel, or the configured one
+func (s *mattermostAlerter) channel(res reservation) string {
+	if target := res.alerterTarget(s.Name()); target != "" {
+		return target
+	}
+	return s.Cfg.Mattermost.Channel
+}
+
+func (s *mattermostAlerter) send(res reservation, resolved bool) bool {
+	return s.post(newChatMessage(res, resolved), s.channel(res))
+}
+
+func (s *mattermostAlerter) post(chat chatMessage, channel string) bool {
+	msg := newSlackMessage(chat)
+	// Mattermost markdown bolds with ** rather than *
+	msg.Text = "*" + msg.Text + "*"
+	msg.Channel = channel
+	msg.Username = s.Cfg.Mattermost.Username
+	msg.IconURL = s.Cfg.Mattermost.IconURL
+
+	if err := postChat(s.client, s.Cfg.Mattermost.WebhookURL, msg); err != nil {
+		l.warn("Unable to post [%s] to Mattermost [%v]", chat.Title, err)
+		return false
+	}
+	l.info("Mattermost message [%s] sent to [%s]", chat.Title, channel)
+	return true
+}
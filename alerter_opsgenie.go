@@ -0,0 +1,152 @@
+package gotel
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ogAPIKey string
+
+func init() {
+	flag.StringVar(&ogAPIKey, "GOTEL_OPSGENIE_API_KEY", "", "Opsgenie API key to use for alerts")
+}
+
+// opsgenieAlerter creates an Opsgenie alert per reservation and alert type and closes it on recovery
+type opsgenieAlerter struct {
+	Cfg    Config
+	client *http.Client
+}
+
+type opsgenieResponder struct {
+	Type     string `json:"type"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Details     map[string]string   `json:"details"`
+	Priority    string              `json:"priority"`
+	Source      string              `json:"source"`
+}
+
+func (s *opsgenieAlerter) Bootstrap() {
+	if ogAPIKey != "" {
+		s.Cfg.Opsgenie.APIKey = ogAPIKey
+	}
+	if s.Cfg.Opsgenie.APIKey == "" {
+		panic("You have Opsgenie alerting enabled but have not provided an apikey under [opsgenie] or the GOTEL_OPSGENIE_API_KEY env/flag")
+	}
+	if s.Cfg.Opsgenie.APIURL == "" {
+		s.Cfg.Opsgenie.APIURL = "https://api.opsgenie.com"
+	}
+	if s.Cfg.Opsgenie.Priority == "" {
+		s.Cfg.Opsgenie.Priority = "P3"
+	}
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	l.info("alerter_opsgenie API_URL [%s]", s.Cfg.Opsgenie.APIURL)
+}
+
+func (s *opsgenieAlerter) Name() string {
+	return "Opsgenie"
+}
+
+// responders maps the owner and notify list to Opsgenie, email addresses are users and anything else is a team. A
+// reservation's route target replaces the notify list.
+func (s *opsgenieAlerter) responders(res reservation) []opsgenieResponder {
+	notify := res.Notify
+	if target := res.alerterTarget(s.Name()); target != "" {
+		notify = target
+	}
+	seen := make(map[string]bool)
+	responders := []opsgenieResponder{}
+	for _, name := range append([]string{res.Owner}, strings.Split(notify, ",")...) {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if strings.Contains(name, "@") {
+			responders = append(responders, opsgenieResponder{Type: "user", Username: name})
+		} else {
+			responders = append(responders, opsgenieResponder{Type: "team", Name: name})
+		}
+	}
+	return responders
+}
+
+func (s *opsgenieAlerter) Alert(res reservation) bool {
+	message := head(fmt.Sprintf("GoTel %s %s/%s", res.AlertType, res.App, res.Component), 130)
+	alert := opsgenieAlert{
+		Message:     message,
+		Alias:       res.alertKey(),
+		Description: res.AlertMessage,
+		Responders:  s.responders(res),
+		Tags:        res.Tags,
+		Details: map[string]string{
+			"app":          res.App,
+			"component":    res.Component,
+			"owner":        res.Owner,
+			"last_checkin": res.formatAlert("{last}"),
+			"since":        res.formatAlert("{since}"),
+			"frequency":    res.formatAlert("{frequency}"),
+		},
+		Priority: s.Cfg.Opsgenie.Priority,
+		Source:   "gotel",
+	}
+	if err := s.post("/v2/alerts", alert); err != nil {
+		l.err("Unable to create Opsgenie alert for app [%s] component [%s] [%v]", res.App, res.Component, err)
+		return false
+	}
+	l.info("Opsgenie alert created [%s]", alert.Alias)
+	return true
+}
+
+func (s *opsgenieAlerter) Resolve(res reservation) bool {
+	alias := res.alertKey()
+	path := "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+	if err := s.post(path, map[string]string{"source": "gotel", "note": res.AlertMessage}); err != nil {
+		l.err("Unable to close Opsgenie alert [%s] [%v]", alias, err)
+		return false
+	}
+	l.info("Opsgenie alert closed [%s]", alias)
+	return true
+}
+
+// post sends a request to the Opsgenie API, which accepts requests with a 202 and processes them asynchronously
+func (s *opsgenieAlerter) post(path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(s.Cfg.Opsgenie.APIURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+s.Cfg.Opsgenie.APIKey)
+
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Opsgenie returned [%s]", resp.Status)
+	}
+	return nil
+}
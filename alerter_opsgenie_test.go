@@ -0,0 +1,85 @@
+package gotel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_opsgenieAlert(t *testing.T) {
+	var (
+		alert    opsgenieAlert
+		paths    []string
+		auth     string
+		closeArg string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		auth = r.Header.Get("Authorization")
+		if r.URL.EscapedPath() == "/v2/alerts" {
+			if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+				t.Errorf("Unable to decode alert [%v]", err)
+			}
+		} else {
+			closeArg = r.URL.Query().Get("identifierType")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	opsgenie := &opsgenieAlerter{}
+	opsgenie.Cfg.Opsgenie.APIKey = "genie"
+	opsgenie.Cfg.Opsgenie.APIURL = srv.URL
+	opsgenie.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", Owner: "jim@example.com", Notify: "ops, jim@example.com",
+		AlertType: alertMissedCheckin, AlertMessage: "failed checkin"}
+	if !opsgenie.Alert(res) {
+		t.Fatalf("Expected the Opsgenie alert to be created")
+	}
+	if !opsgenie.Resolve(res) {
+		t.Fatalf("Expected the Opsgenie alert to be closed")
+	}
+
+	if auth != "GenieKey genie" {
+		t.Fatalf("Unexpected Authorization header [%s]", auth)
+	}
+	if alert.Alias != "gotel/jimtest/monitor/missed_checkin" || alert.Priority != "P3" || alert.Description != "failed checkin" {
+		t.Fatalf("Unexpected alert [%+v]", alert)
+	}
+	expected := []opsgenieResponder{{Type: "user", Username: "jim@example.com"}, {Type: "team", Name: "ops"}}
+	if len(alert.Responders) != 2 || alert.Responders[0] != expected[0] || alert.Responders[1] != expected[1] {
+		t.Fatalf("Unexpected responders [%+v]", alert.Responders)
+	}
+	if len(paths) != 2 || paths[1] != "/v2/alerts/gotel%2Fjimtest%2Fmonitor%2Fmissed_checkin/close" || closeArg != "alias" {
+		t.Fatalf("Unexpected close request [%v] [%s]", paths, closeArg)
+	}
+}
+
+func Test_opsgenieLongMessage(t *testing.T) {
+	var alert opsgenieAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Unable to decode alert [%v]", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	opsgenie := &opsgenieAlerter{}
+	opsgenie.Cfg.Opsgenie.APIKey = "genie"
+	opsgenie.Cfg.Opsgenie.APIURL = srv.URL
+	opsgenie.Bootstrap()
+
+	// the prefix is 29 bytes so the cut at 130 lands inside a two byte ü
+	res := reservation{App: "jimtest", Component: strings.Repeat("ü", 60), AlertType: alertMissedCheckin}
+	if !opsgenie.Alert(res) {
+		t.Fatalf("Expected the Opsgenie alert to be created")
+	}
+	if len(alert.Message) != 129 || !utf8.ValidString(alert.Message) || strings.ContainsRune(alert.Message, utf8.RuneError) {
+		t.Fatalf("Expected the message cut to 129 bytes on a rune boundary got %d bytes [%s]", len(alert.Message), alert.Message)
+	}
+}
@@ -1,18 +1,26 @@
 package gotel
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
-	"github.com/stvp/pager"
+	"fmt"
+	"net/http"
+	"time"
 )
 
 var (
 	pdServiceKey string
+	// pdEventsURL is the PagerDuty generic events API
+	pdEventsURL = "https://events.pagerduty.com/generic/2010-04-15/create_event.json"
 )
 
 func init() {
 	flag.StringVar(&pdServiceKey, "GOTEL_PD_SERVICE_KEY", "", "PagerDuty service key to use for alerts")
 }
 
+// pagerDutyAlerter triggers incidents keyed by the reservation's alert key, so the resolve needs nothing from the
+// trigger and works after a restart or on a new coordinator
 type pagerDutyAlerter struct {
 	Cfg Config
 }
@@ -27,14 +35,21 @@ func (s *pagerDutyAlerter) Name() string {
 	return "PagerDuty"
 }
 
-func (s *pagerDutyAlerter) Alert(res reservation) bool {
+// serviceKey is the reservation's routed service key, or the configured one
+func (s *pagerDutyAlerter) serviceKey(res reservation) string {
+	if target := res.alerterTarget(s.Name()); target != "" {
+		return target
+	}
+	return s.Cfg.PagerDuty.ServiceKey
+}
 
-	l.info("PagerDuty API key [%s]", s.Cfg.PagerDuty.ServiceKey)
+func (s *pagerDutyAlerter) Alert(res reservation) bool {
 
-	pager.ServiceKey = s.Cfg.PagerDuty.ServiceKey
-	incidentKey, err := pager.Trigger(res.AlertMessage)
+	serviceKey := s.serviceKey(res)
+	l.info("PagerDuty API key [%s]", serviceKey)
 
-	if err != nil {
+	incidentKey := res.alertKey()
+	if err := s.event(serviceKey, "trigger", incidentKey, res.AlertMessage); err != nil {
 		l.err("[ERROR] Unable to create PagerDuty alert for job [%s] component [%s] error [%v]\n", res.App,
 			res.Component, err)
 		return false
@@ -42,3 +57,36 @@ func (s *pagerDutyAlerter) Alert(res reservation) bool {
 	l.info("PagerDuty incident key created %s\n", incidentKey)
 	return true
 }
+
+func (s *pagerDutyAlerter) Resolve(res reservation) bool {
+	incidentKey := res.alertKey()
+	if err := s.event(s.serviceKey(res), "resolve", incidentKey, res.AlertMessage); err != nil {
+		l.err("[ERROR] Unable to resolve PagerDuty incident [%s] error [%v]\n", incidentKey, err)
+		return false
+	}
+	l.info("PagerDuty incident resolved %s\n", incidentKey)
+	return true
+}
+
+// event sends a trigger or resolve to the generic events API
+func (s *pagerDutyAlerter) event(serviceKey, eventType, incidentKey, description string) error {
+	body, err := json.Marshal(map[string]string{
+		"service_key":  serviceKey,
+		"event_type":   eventType,
+		"incident_key": incidentKey,
+		"description":  description,
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(pdEventsURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("PagerDuty returned [%s]", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package gotel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_pagerDuty(t *testing.T) {
+	events := []map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Unable to decode event [%v]", err)
+		}
+		events = append(events, event)
+	}))
+	defer srv.Close()
+	oldURL := pdEventsURL
+	pdEventsURL = srv.URL
+	defer func() { pdEventsURL = oldURL }()
+
+	pd := &pagerDutyAlerter{}
+	pd.Cfg.PagerDuty.ServiceKey = "servicekey"
+	res := reservation{App: "jimtest", Component: "monitor", AlertType: alertMissedCheckin}
+	if !pd.Alert(res) {
+		t.Fatalf("Expected trigger to succeed")
+	}
+
+	// a new alerter, as after a restart, resolves the same incident
+	pd = &pagerDutyAlerter{}
+	pd.Cfg.PagerDuty.ServiceKey = "servicekey"
+	if !pd.Resolve(res) {
+		t.Fatalf("Expected resolve to succeed")
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events got %d", len(events))
+	}
+	if events[0]["event_type"] != "trigger" || events[0]["incident_key"] != "gotel/jimtest/monitor/missed_checkin" {
+		t.Fatalf("Unexpected trigger event [%v]", events[0])
+	}
+	if events[1]["event_type"] != "resolve" || events[1]["incident_key"] != events[0]["incident_key"] ||
+		events[1]["service_key"] != "servicekey" {
+		t.Fatalf("Unexpected resolve event [%v]", events[1])
+	}
+}
+
+func Test_pagerDutyV2(t *testing.T) {
+	events := []pdEvent{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := pdEvent{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Unable to decode event [%v]", err)
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	oldURL := pdEventsV2URL
+	pdEventsV2URL = srv.URL
+	defer func() { pdEventsV2URL = oldURL }()
+
+	pd := &pagerDutyV2Alerter{}
+	pd.Cfg.PagerDuty.ServiceKey = "routingkey"
+	pd.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", AlertType: alertMissedCheckin, AlertMessage: "failed checkin",
+		NumCheckins: 3}
+	if !pd.Alert(res) {
+		t.Fatalf("Expected the trigger to be sent")
+	}
+	res.Severity = "warning"
+	res.Alerters = []alerterRoute{{Name: "PagerDuty", Target: "teamkey"}}
+	if !pd.Alert(res) || !pd.Resolve(res) {
+		t.Fatalf("Expected the routed trigger and resolve to be sent")
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events got %d", len(events))
+	}
+	first := events[0]
+	if first.RoutingKey != "routingkey" || first.EventAction != "trigger" || first.DedupKey != "gotel/jimtest/monitor/missed_checkin" {
+		t.Fatalf("Unexpected trigger [%+v]", first)
+	}
+	if first.Payload.Severity != "critical" || first.Payload.Summary != "failed checkin" || first.Payload.CustomDetails["checkins"] != "3" {
+		t.Fatalf("Unexpected payload [%+v]", first.Payload)
+	}
+	if events[1].RoutingKey != "teamkey" || events[1].Payload.Severity != "warning" {
+		t.Fatalf("Unexpected routed trigger [%+v]", events[1])
+	}
+	if events[2].EventAction != "resolve" || events[2].DedupKey != first.DedupKey || events[2].Payload != nil {
+		t.Fatalf("Unexpected resolve [%+v]", events[2])
+	}
+}
+
+func Test_pagerDutyV2Summary(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"short", "failed checkin", "failed checkin"},
+		{"ascii", strings.Repeat("a", 1030), strings.Repeat("a", 1024)},
+		{"multibyte", strings.Repeat("a", 1023) + "é", strings.Repeat("a", 1023)},
+	}
+	for _, tt := range tests {
+		got := head(tt.in, 1024)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Fatalf("%s: unexpected summary of %d bytes", tt.name, len(got))
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package gotel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// pdEventsV2URL is the PagerDuty Events API v2 endpoint
+var pdEventsV2URL = "https://events.pagerduty.com/v2/enqueue"
+
+// pdSeverities are the severities the Events API v2 accepts
+var pdSeverities = map[string]bool{"critical": true, "error": true, "warning": true, "info": true}
+
+func validSeverity(severity string) bool {
+	return pdSeverities[severity]
+}
+
+// pagerDutyV2Alerter sends Events API v2 events keyed by the reservation's alert key, so PagerDuty groups repeats
+// into one incident and a resolve needs no local state
+type pagerDutyV2Alerter struct {
+	Cfg    Config
+	client *http.Client
+}
+
+type pdPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component"`
+	Group         string            `json:"group"`
+	Class         string            `json:"class"`
+	CustomDetails map[string]string `json:"custom_details"`
+}
+
+type pdEvent struct {
+	RoutingKey  string     `json:"routing_key"`
+	EventAction string     `json:"event_action"`
+	DedupKey    string     `json:"dedup_key"`
+	Payload     *pdPayload `json:"payload,omitempty"`
+}
+
+func (s *pagerDutyV2Alerter) Bootstrap() {
+	if pdServiceKey != "" {
+		s.Cfg.PagerDuty.ServiceKey = pdServiceKey
+	}
+	if s.Cfg.PagerDuty.Severity == "" {
+		s.Cfg.PagerDuty.Severity = "critical"
+	}
+	if !validSeverity(s.Cfg.PagerDuty.Severity) {
+		panic("Invalid severity under [pagerduty], must be critical, error, warning or info")
+	}
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	l.info("alerter_pagerduty using Events API v2")
+}
+
+func (s *pagerDutyV2Alerter) Name() string {
+	return "PagerDuty"
+}
+
+// routingKey is the reservation's routed integration key, or the configured one
+func (s *pagerDutyV2Alerter) routingKey(res reservation) string {
+	if target := res.alerterTarget(s.Name()); target != "" {
+		return target
+	}
+	return s.Cfg.PagerDuty.ServiceKey
+}
+
+func (s *pagerDutyV2Alerter) Alert(res reservation) bool {
+	ip, err := externalIP()
+	if err != nil {
+		ip = "N/A"
+	}
+	severity := res.Severity
+	if severity == "" {
+		severity = s.Cfg.PagerDuty.Severity
+	}
+	summary := head(res.AlertMessage, 1024)
+	event := pdEvent{
+		RoutingKey:  s.routingKey(res),
+		EventAction: "trigger",
+		DedupKey:    res.alertKey(),
+		Payload: &pdPayload{
+			Summary:   summary,
+			Source:    ip,
+			Severity:  severity,
+			Component: res.Component,
+			Group:     res.App,
+			Class:     res.AlertType,
+			CustomDetails: map[string]string{
+				"owner":        res.Owner,
+				"last_checkin": res.formatAlert("{last}"),
+				"since":        res.formatAlert("{since}"),
+				"frequency":    res.formatAlert("{frequency}"),
+				"checkins":     strconv.Itoa(res.NumCheckins),
+				"srv":          ip,
+			},
+		},
+	}
+	if err = s.send(event); err != nil {
+		l.err("[ERROR] Unable to create PagerDuty alert for job [%s] component [%s] error [%v]\n", res.App,
+			res.Component, err)
+		return false
+	}
+	l.info("PagerDuty event triggered %s\n", event.DedupKey)
+	return true
+}
+
+func (s *pagerDutyV2Alerter) Resolve(res reservation) bool {
+	event := pdEvent{RoutingKey: s.routingKey(res), EventAction: "resolve", DedupKey: res.alertKey()}
+	if err := s.send(event); err != nil {
+		l.err("[ERROR] Unable to resolve PagerDuty event [%s] error [%v]\n", event.DedupKey, err)
+		return false
+	}
+	l.info("PagerDuty event resolved %s\n", event.DedupKey)
+	return true
+}
+
+// send enqueues an event, PagerDuty accepts it with a 202
+func (s *pagerDutyV2Alerter) send(event pdEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(pdEventsV2URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("PagerDuty returned [%s]", resp.Status)
+	}
+	return nil
+}
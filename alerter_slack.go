@@ -0,0 +1,105 @@
+package gotel
+
+import (
+	"net/http"
+	"time"
+)
+
+type slackAlerter struct {
+	Cfg    Config
+	client *http.Client
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Fallback string       `json:"fallback"`
+	Color    string       `json:"color"`
+	Text     string       `json:"text"`
+	Fields   []slackField `json:"fields"`
+}
+
+// slackMessage is an incoming webhook payload, Mattermost takes the same one
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	IconURL     string            `json:"icon_url,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// newSlackMessage renders a chat message as a Slack attachment
+func newSlackMessage(msg chatMessage) slackMessage {
+	fields := []slackField{}
+	for _, field := range msg.Fields {
+		fields = append(fields, slackField{Title: field.Title, Value: field.Value, Short: true})
+	}
+	return slackMessage{
+		Text: "*" + msg.Title + "*",
+		Attachments: []slackAttachment{{
+			Fallback: msg.Text,
+			Color:    "#" + msg.color(),
+			Text:     msg.Text,
+			Fields:   fields,
+		}},
+	}
+}
+
+func (s *slackAlerter) Bootstrap() {
+	if s.Cfg.Slack.WebhookURL == "" {
+		panic("You have Slack alerting enabled but have not provided a webhookurl under [slack]")
+	}
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	l.info("alerter_slack default channel [%s]", s.Cfg.Slack.Channel)
+}
+
+func (s *slackAlerter) Name() string {
+	return "Slack"
+}
+
+func (s *slackAlerter) Alert(res reservation) bool {
+	return s.send(res, false)
+}
+
+func (s *slackAlerter) Resolve(res reservation) bool {
+	return s.send(res, true)
+}
+
+func (s *slackAlerter) Recipients(res reservation) []string {
+	return []string{s.channel(res)}
+}
+
+func (s *slackAlerter) Digest(digests []digest) map[string]bool {
+	return postChatDigests(digests, s.post)
+}
+
+// channel is the reservation's routed channel, or the configured one
+func (s *slackAlerter) channel(res reservation) string {
+	if target := res.alerterTarget(s.Name()); target != "" {
+		return target
+	}
+	return s.Cfg.Slack.Channel
+}
+
+func (s *slackAlerter) send(res reservation, resolved bool) bool {
+	return s.post(newChatMessage(res, resolved), s.channel(res))
+}
+
+func (s *slackAlerter) post(chat chatMessage, channel string) bool {
+	msg := newSlackMessage(chat)
+	msg.Channel = channel
+	msg.Username = s.Cfg.Slack.Username
+	msg.IconEmoji = s.Cfg.Slack.IconEmoji
+
+	if err := postChat(s.client, s.Cfg.Slack.WebhookURL, msg); err != nil {
+		l.warn("Unable to post [%s] to Slack [%v]", chat.Title, err)
+		return false
+	}
+	l.info("Slack message [%s] sent to [%s]", chat.Title, channel)
+	return true
+}
@@ -0,0 +1,48 @@
+package gotel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_slackAlert(t *testing.T) {
+	msgs := []slackMessage{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := slackMessage{}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("Unable to decode Slack message [%v]", err)
+		}
+		msgs = append(msgs, msg)
+	}))
+	defer srv.Close()
+
+	slack := &slackAlerter{}
+	slack.Cfg.Slack.WebhookURL = srv.URL
+	slack.Cfg.Slack.Channel = "#ops"
+	slack.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", Owner: "jim", AlertMessage: "failed checkin"}
+	if !slack.Alert(res) {
+		t.Fatalf("Expected the Slack alert to be sent")
+	}
+	res.Alerters = []alerterRoute{{Name: "slack", Target: "#jimteam"}}
+	if !slack.Resolve(res) {
+		t.Fatalf("Expected the Slack recovery to be sent")
+	}
+
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages got %d", len(msgs))
+	}
+	if msgs[0].Channel != "#ops" || msgs[1].Channel != "#jimteam" {
+		t.Fatalf("Expected default then routed channel got [%s] [%s]", msgs[0].Channel, msgs[1].Channel)
+	}
+	if msgs[0].Attachments[0].Color != "#D9534F" || msgs[1].Attachments[0].Color != "#2EB886" {
+		t.Fatalf("Unexpected colors [%+v]", msgs)
+	}
+	fields := msgs[0].Attachments[0].Fields
+	if len(fields) != 5 || fields[4].Value != "jim" {
+		t.Fatalf("Unexpected fields [%+v]", fields)
+	}
+}
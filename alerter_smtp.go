@@ -1,11 +1,15 @@
 package gotel
 
 import (
-	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/smtp"
-	"strings"
+	"strconv"
 	"time"
 )
 
@@ -16,6 +20,9 @@ var (
 	smtpPort int
 )
 
+// smtpTimeout bounds dialing the SMTP server
+const smtpTimeout = 10 * time.Second
+
 func init() {
 	flag.StringVar(&smtpHost, "GOTEL_SMTP_HOST", "", "Host of the SMTP server for sending mail")
 	flag.StringVar(&smtpUser, "GOTEL_SMTP_USER", "", "SMTP user name")
@@ -27,11 +34,45 @@ func (s *smtpAlerter) Bootstrap() {
 	if smtpHost == "" {
 		panic("You have SMTP alerting enabled but have not provided the GOTEL_SMTP_HOST env/flag")
 	}
-	l.info("alerter_smtp SMTP_HOST [%s] SMTP_USER [%s]", smtpHost, smtpUser)
+	switch s.Cfg.SMTP.TLS {
+	case "", "starttls", "tls", "none":
+	default:
+		panic("Invalid tls under [smtp], must be starttls, tls or none")
+	}
+	// net/smtp won't send PLAIN auth over an unencrypted connection, so every send would fail
+	if s.Cfg.SMTP.TLS == "none" && smtpUser != "" && !s.Cfg.SMTP.NoAuth {
+		panic("SMTP auth needs TLS, set tls to starttls or tls under [smtp], or noauth = true for a relay without auth")
+	}
+
+	s.tlsConfig = &tls.Config{ServerName: smtpHost, InsecureSkipVerify: s.Cfg.SMTP.InsecureSkipVerify}
+	if s.Cfg.SMTP.CAFile != "" {
+		pem, err := ioutil.ReadFile(s.Cfg.SMTP.CAFile)
+		if err != nil {
+			l.err("Unable to read SMTP cafile [%s] [%v]", s.Cfg.SMTP.CAFile, err)
+			panic("Unable to read the SMTP cafile")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			panic("The SMTP cafile has no PEM certificates")
+		}
+		s.tlsConfig.RootCAs = pool
+	}
+
+	templates, err := parseEmailTemplates(s.Cfg)
+	if err != nil {
+		l.err("Unable to parse the SMTP email templates [%v]", err)
+		panic("Unable to parse the SMTP email templates")
+	}
+	s.templates = templates
+	l.info("alerter_smtp SMTP_HOST [%s] SMTP_USER [%s] TLS [%s]", smtpHost, smtpUser, s.Cfg.SMTP.TLS)
 }
 
 type smtpAlerter struct {
 	Cfg Config
+	// Store is where the checkin history in the emails comes from
+	Store     Store
+	tlsConfig *tls.Config
+	templates *emailTemplates
 }
 
 func (s *smtpAlerter) Name() string {
@@ -39,6 +80,68 @@ func (s *smtpAlerter) Name() string {
 }
 
 func (s *smtpAlerter) Alert(res reservation) bool {
+	return s.send(res, alertTitle(res), false)
+}
+
+func (s *smtpAlerter) Resolve(res reservation) bool {
+	return s.send(res, "Job Recovered", true)
+}
+
+// dial connects to the SMTP server, upgrading to TLS and authenticating as configured. tls is implicit TLS, e.g. on
+// port 465, starttls requires the upgrade, none never upgrades, and by default STARTTLS is used when it's offered.
+func (s *smtpAlerter) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(smtpHost, strconv.Itoa(smtpPort))
+	tlsConfig := s.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: smtpHost}
+	}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.Cfg.SMTP.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, smtpHost)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if s.Cfg.SMTP.TLS != "tls" && s.Cfg.SMTP.TLS != "none" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		} else if s.Cfg.SMTP.TLS == "starttls" {
+			client.Close()
+			return nil, errors.New("server does not support STARTTLS")
+		}
+	}
+
+	// relays that don't take auth can skip it with noauth or by not giving a user
+	if !s.Cfg.SMTP.NoAuth && smtpUser != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err = client.Auth(smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+	}
+	return client, nil
+}
+
+// send emails the reservation's alert message to everyone on its notify list, over one connection. Each address is
+// sent to on its own, the alert counts as sent when anyone got it so they aren't emailed again on the next check.
+func (s *smtpAlerter) send(res reservation, event string, resolved bool) bool {
 
 	ip, err := externalIP()
 	if err != nil {
@@ -47,27 +150,120 @@ func (s *smtpAlerter) Alert(res reservation) bool {
 
 	l.info("building SMTP alert for app [%s] component [%s] on ip [%s]\n", res.App, res.Component, ip)
 
-	peopleToNotify := strings.Split(res.Notify, ",")
+	notify := s.notify(res)
+	data := newEmailData(s.Store, res, event, resolved, notify, s.baseURL(ip))
 
-	// split on the notifiers and send a unique email to each.
-	for _, emailAddyRaw := range peopleToNotify {
+	// render a unique email for each of the notifiers before anyone is sent one
+	mails := []smtpMessage{}
+	for _, emailAddy := range splitRecipients(notify) {
+		message, err := s.templates.render(data, s.Cfg.SMTP.FromAddress, s.Cfg.SMTP.ReplyTO, emailAddy)
+		if err != nil {
+			l.err("Unable to render email for app [%s] component [%s] [%v]", res.App, res.Component, err)
+			return false
+		}
+		mails = append(mails, smtpMessage{to: emailAddy, message: message})
+	}
+	if len(mails) == 0 {
+		return false
+	}
+	return len(s.deliver(mails)) > 0
+}
+
+// notify is the reservation's routed notify list, or its own
+func (s *smtpAlerter) notify(res reservation) string {
+	if target := res.alerterTarget(s.Name()); target != "" {
+		return target
+	}
+	return res.Notify
+}
 
-		smtpPair := fmt.Sprintf("%s:%d", smtpHost, smtpPort)
-		emailAddy := strings.TrimSpace(emailAddyRaw)
-		now := time.Now().Format(time.RFC822) // in case email delivery delay, let them know the actual date
-		subject := "Job Failed to checkin"
-		body := fmt.Sprintf("%s\n\nAlert time is [%s]\n\nNotification list [%s]", res.AlertMessage, now, res.Notify)
-		message := bytes.NewBufferString(fmt.Sprintf("Subject: %s\r\nFrom: %s\r\nReply-to: %s\r\nTo: %s\r\n\r\n%s",
-			subject, s.Cfg.SMTP.FromAddress, s.Cfg.SMTP.ReplyTO, emailAddy, body))
+func (s *smtpAlerter) baseURL(ip string) string {
+	if s.Cfg.Main.BaseURL != "" {
+		return s.Cfg.Main.BaseURL
+	}
+	return fmt.Sprintf("http://%s:8080", ip)
+}
 
-		// Now push out the complete mail message
-		auth := smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)
-		if err = smtp.SendMail(smtpPair, auth, s.Cfg.SMTP.FromAddress, []string{emailAddy}, message.Bytes()); err != nil {
-			l.warn("[WARN] Unable to write to mail server: host: [%s] user: [%s] err: [%v]\n", smtpHost, smtpUser, err)
-			return false
+func (s *smtpAlerter) Recipients(res reservation) []string {
+	return splitRecipients(s.notify(res))
+}
+
+// Digest emails each recipient a single message listing their failures, over one connection
+func (s *smtpAlerter) Digest(digests []digest) map[string]bool {
+	ip, err := externalIP()
+	if err != nil {
+		ip = "N/A"
+	}
+	mails := []smtpMessage{}
+	for _, d := range digests {
+		data := []emailData{}
+		for _, res := range d.failures {
+			data = append(data, newEmailData(nil, res, alertTitle(res), false, d.recipient, s.baseURL(ip)))
 		}
-		l.info("Email sent for app [%s] component [%s]\n", res.App, res.Component)
+		message, err := s.templates.renderDigest(data, s.Cfg.SMTP.FromAddress, s.Cfg.SMTP.ReplyTO, d.recipient)
+		if err != nil {
+			l.err("Unable to render digest email for [%s] [%v]", d.recipient, err)
+			continue
+		}
+		mails = append(mails, smtpMessage{to: d.recipient, message: message})
+	}
+	if len(mails) == 0 {
+		return map[string]bool{}
 	}
+	return s.deliver(mails)
+}
+
+// smtpMessage is a rendered email and who it goes to
+type smtpMessage struct {
+	to      string
+	message []byte
+}
 
-	return true
+// deliver sends each mail on its own over one connection, moving on to a new connection when a failed send takes
+// the old one down. It returns the recipients that got theirs.
+func (s *smtpAlerter) deliver(mails []smtpMessage) map[string]bool {
+	delivered := make(map[string]bool)
+	client, err := s.dial()
+	if err != nil {
+		l.warn("[WARN] Unable to connect to mail server: host: [%s] user: [%s] err: [%v]\n", smtpHost, smtpUser, err)
+		return delivered
+	}
+	for _, m := range mails {
+		if err = sendMessage(client, s.Cfg.SMTP.FromAddress, m.to, m.message); err != nil {
+			l.warn("[WARN] Unable to send to [%s]: host: [%s] user: [%s] err: [%v]\n", m.to, smtpHost, smtpUser, err)
+			if client.Reset() != nil {
+				client.Close()
+				if client, err = s.dial(); err != nil {
+					l.warn("[WARN] Unable to reconnect to mail server: host: [%s] err: [%v]\n", smtpHost, err)
+					return delivered
+				}
+			}
+			continue
+		}
+		delivered[m.to] = true
+		l.info("Email sent to [%s]\n", m.to)
+	}
+	if err = client.Quit(); err != nil {
+		l.warn("[WARN] Unable to close mail server connection: host: [%s] err: [%v]\n", smtpHost, err)
+		client.Close()
+	}
+	return delivered
+}
+
+// sendMessage sends one message on an open connection, which can be reused for the next one afterwards
+func sendMessage(client *smtp.Client, from, to string, msg []byte) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
 }
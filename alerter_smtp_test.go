@@ -0,0 +1,253 @@
+package gotel
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP is a bare SMTP server that records connections and recipients
+type fakeSMTP struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns int
+	rcpts []string
+	msgs  []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen [%v]", err)
+	}
+	f := &fakeSMTP{ln: ln}
+	go f.serve()
+	return f
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns++
+		f.mu.Unlock()
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "RCPT TO:<BOUNCE"):
+			reply("550 no such user")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			f.mu.Lock()
+			f.rcpts = append(f.rcpts, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			f.mu.Unlock()
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var msg strings.Builder
+			for {
+				data, err := r.ReadString('\n')
+				if err != nil || data == ".\r\n" {
+					break
+				}
+				msg.WriteString(data)
+			}
+			f.mu.Lock()
+			f.msgs = append(f.msgs, msg.String())
+			f.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (f *fakeSMTP) sent() (int, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns, f.rcpts
+}
+
+func useFakeSMTP(t *testing.T, f *fakeSMTP) func() {
+	oldHost, oldPort, oldUser := smtpHost, smtpPort, smtpUser
+	host, port, _ := net.SplitHostPort(f.ln.Addr().String())
+	smtpHost, smtpUser = host, ""
+	smtpPort, _ = strconv.Atoi(port)
+	return func() {
+		f.ln.Close()
+		smtpHost, smtpPort, smtpUser = oldHost, oldPort, oldUser
+	}
+}
+
+func Test_smtpReusesConnection(t *testing.T) {
+	f := newFakeSMTP(t)
+	defer useFakeSMTP(t, f)()
+
+	s := &smtpAlerter{}
+	s.Cfg.SMTP.FromAddress = "gotel@example.com"
+	s.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", Notify: "jim@example.com, bob@example.com,sue@example.com"}
+	if !s.Alert(res) {
+		t.Fatalf("Expected the emails to be sent")
+	}
+	conns, rcpts := f.sent()
+	if conns != 1 {
+		t.Fatalf("Expected one connection for every recipient got %d", conns)
+	}
+	if strings.Join(rcpts, ",") != "jim@example.com,bob@example.com,sue@example.com" {
+		t.Fatalf("Unexpected recipients [%v]", rcpts)
+	}
+}
+
+func Test_smtpPartialDelivery(t *testing.T) {
+	f := newFakeSMTP(t)
+	defer useFakeSMTP(t, f)()
+
+	s := &smtpAlerter{}
+	s.Cfg.SMTP.FromAddress = "gotel@example.com"
+	s.Bootstrap()
+
+	// the bounced address and the empty one from the trailing comma don't stop the others getting the alert
+	res := reservation{App: "jimtest", Component: "monitor", Notify: "jim@example.com, bounce@example.com, sue@example.com,"}
+	if !s.Alert(res) {
+		t.Fatalf("Expected the alert to count as sent")
+	}
+	conns, rcpts := f.sent()
+	if conns != 1 || strings.Join(rcpts, ",") != "jim@example.com,sue@example.com" {
+		t.Fatalf("Unexpected delivery, %d connections to [%v]", conns, rcpts)
+	}
+
+	res.Notify = "bounce@example.com"
+	if s.Alert(res) {
+		t.Fatalf("Expected the alert to fail when nobody got it")
+	}
+}
+
+func Test_smtpRequireStartTLS(t *testing.T) {
+	f := newFakeSMTP(t)
+	defer useFakeSMTP(t, f)()
+
+	s := &smtpAlerter{}
+	s.Cfg.SMTP.TLS = "starttls"
+	s.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", Notify: "jim@example.com"}
+	if s.Alert(res) {
+		t.Fatalf("Expected sending to fail without STARTTLS")
+	}
+	if _, rcpts := f.sent(); len(rcpts) != 0 {
+		t.Fatalf("Expected nothing sent in plain text got [%v]", rcpts)
+	}
+}
+
+func Test_smtpAuthNeedsTLS(t *testing.T) {
+	tests := []struct {
+		name   string
+		tls    string
+		user   string
+		noauth bool
+		panics bool
+	}{
+		{"plain text with auth", "none", "jim", false, true},
+		{"plain text relay", "none", "", false, false},
+		{"plain text noauth", "none", "jim", true, false},
+		{"starttls with auth", "starttls", "jim", false, false},
+	}
+
+	for _, tt := range tests {
+		f := newFakeSMTP(t)
+		restore := useFakeSMTP(t, f)
+		smtpUser = tt.user
+
+		s := &smtpAlerter{}
+		s.Cfg.SMTP.TLS = tt.tls
+		s.Cfg.SMTP.NoAuth = tt.noauth
+		panicked := func() (panicked bool) {
+			defer func() { panicked = recover() != nil }()
+			s.Bootstrap()
+			return false
+		}()
+		restore()
+		if panicked != tt.panics {
+			t.Fatalf("%s: expected panic %v got %v", tt.name, tt.panics, panicked)
+		}
+	}
+}
+
+func Test_smtpRenderBeforeSending(t *testing.T) {
+	f := newFakeSMTP(t)
+	defer useFakeSMTP(t, f)()
+
+	s := &smtpAlerter{}
+	s.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", Notify: "jim@example.com, ops@example.com",
+		EmailSubject: "{{.Nope"}
+	if s.Alert(res) {
+		t.Fatalf("Expected an email that won't render not to be sent")
+	}
+	if conns, rcpts := f.sent(); conns != 0 || len(rcpts) != 0 {
+		t.Fatalf("Expected nothing sent got %d connections to [%v]", conns, rcpts)
+	}
+}
+
+func Test_smtpDigest(t *testing.T) {
+	f := newFakeSMTP(t)
+	defer useFakeSMTP(t, f)()
+
+	s := &smtpAlerter{}
+	s.Cfg.SMTP.FromAddress = "gotel@example.com"
+	s.Bootstrap()
+
+	failures := []reservation{
+		{App: "billing", Component: "import", Notify: "ops@example.com", AlertType: alertMissedCheckin},
+		{App: "search", Component: "index", Notify: "ops@example.com", AlertType: alertMaxRuntime},
+	}
+	if recipients := s.Recipients(failures[0]); len(recipients) != 1 || recipients[0] != "ops@example.com" {
+		t.Fatalf("Unexpected recipients [%v]", recipients)
+	}
+	digests := []digest{
+		{recipient: "ops@example.com", failures: failures},
+		{recipient: "bounce@example.com", failures: failures[:1]},
+		{recipient: "jim@example.com", failures: failures[1:]},
+	}
+	delivered := s.Digest(digests)
+	if len(delivered) != 2 || !delivered["ops@example.com"] || !delivered["jim@example.com"] {
+		t.Fatalf("Unexpected digest deliveries [%v]", delivered)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conns != 1 || len(f.msgs) != 2 {
+		t.Fatalf("Expected two digest emails over one connection got %d over %d", len(f.msgs), f.conns)
+	}
+	for _, want := range []string{"Subject: 2 jobs failing", "billing/import", "search/index"} {
+		if !strings.Contains(f.msgs[0], want) {
+			t.Fatalf("Expected the digest to contain [%s] got [%s]", want, f.msgs[0])
+		}
+	}
+}
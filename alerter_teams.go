@@ -0,0 +1,97 @@
+package gotel
+
+import (
+	"net/http"
+	"time"
+)
+
+// teamsAlerter posts connector cards to Microsoft Teams incoming webhooks
+type teamsAlerter struct {
+	Cfg    Config
+	client *http.Client
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	Summary    string         `json:"summary"`
+	ThemeColor string         `json:"themeColor"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text"`
+	Sections   []teamsSection `json:"sections"`
+}
+
+// newTeamsCard renders a chat message as a connector card
+func newTeamsCard(msg chatMessage) teamsCard {
+	facts := []teamsFact{}
+	for _, field := range msg.Fields {
+		facts = append(facts, teamsFact{Name: field.Title, Value: field.Value})
+	}
+	return teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    msg.Title,
+		ThemeColor: msg.color(),
+		Title:      msg.Title,
+		Text:       msg.Text,
+		Sections:   []teamsSection{{Facts: facts}},
+	}
+}
+
+func (s *teamsAlerter) Bootstrap() {
+	if s.Cfg.Teams.WebhookURL == "" {
+		panic("You have Teams alerting enabled but have not provided a webhookurl under [teams]")
+	}
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	l.info("alerter_teams enabled")
+}
+
+func (s *teamsAlerter) Name() string {
+	return "Teams"
+}
+
+func (s *teamsAlerter) Alert(res reservation) bool {
+	return s.send(res, false)
+}
+
+func (s *teamsAlerter) Resolve(res reservation) bool {
+	return s.send(res, true)
+}
+
+// Recipients are webhook urls, Teams webhooks belong to a channel so a route target is another webhook url
+func (s *teamsAlerter) Recipients(res reservation) []string {
+	return []string{s.url(res)}
+}
+
+func (s *teamsAlerter) Digest(digests []digest) map[string]bool {
+	return postChatDigests(digests, s.post)
+}
+
+func (s *teamsAlerter) url(res reservation) string {
+	if target := res.alerterTarget(s.Name()); target != "" {
+		return target
+	}
+	return s.Cfg.Teams.WebhookURL
+}
+
+func (s *teamsAlerter) send(res reservation, resolved bool) bool {
+	return s.post(newChatMessage(res, resolved), s.url(res))
+}
+
+func (s *teamsAlerter) post(chat chatMessage, url string) bool {
+	if err := postChat(s.client, url, newTeamsCard(chat)); err != nil {
+		l.warn("Unable to post [%s] to Teams [%v]", chat.Title, err)
+		return false
+	}
+	l.info("Teams card [%s] sent", chat.Title)
+	return true
+}
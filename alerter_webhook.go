@@ -0,0 +1,193 @@
+package gotel
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	// webhookBackoff is how long to wait before each retry
+	webhookBackoff = 250 * time.Millisecond
+	// webhookRetryBudget bounds how long one alert spends on a URL, attempts and retries included. Alerts are sent from
+	// the job checker so a slow URL holds up every other reservation's check
+	webhookRetryBudget = 5 * time.Second
+)
+
+// webhookFuncs are available to body templates, {{json .Message}} writes a quoted and escaped JSON string
+var webhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// webhookPayload is the JSON document sent to webhooks, and what a body template is executed with
+type webhookPayload struct {
+	Event       string      `json:"event"` // trigger or resolve
+	AlertType   string      `json:"alert_type"`
+	App         string      `json:"app"`
+	Component   string      `json:"component"`
+	Owner       string      `json:"owner"`
+	Notify      string      `json:"notify"`
+	Message     string      `json:"message"`
+	LastCheckin int64       `json:"last_checkin"`
+	Timestamp   int64       `json:"timestamp"`
+	Reservation reservation `json:"reservation"`
+}
+
+type webhookAlerter struct {
+	Cfg    Config
+	tmpl   *template.Template
+	client *http.Client
+}
+
+func (s *webhookAlerter) Bootstrap() {
+	if len(s.Cfg.Webhook.URL) == 0 {
+		panic("You have webhook alerting enabled but have not provided a url under [webhook]")
+	}
+	if s.Cfg.Webhook.BodyTemplate != "" {
+		tmpl, err := template.New("webhook").Funcs(webhookFuncs).Parse(s.Cfg.Webhook.BodyTemplate)
+		if err != nil {
+			l.err("Invalid webhook body template [%v]", err)
+			panic("Unable to parse the webhook body template")
+		}
+		s.tmpl = tmpl
+	}
+	timeout := s.Cfg.Webhook.TimeoutSeconds
+	if timeout == 0 {
+		timeout = 10
+	}
+	s.client = &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	l.info("alerter_webhook URLs [%s]", strings.Join(s.Cfg.Webhook.URL, ", "))
+}
+
+func (s *webhookAlerter) Name() string {
+	return "Webhook"
+}
+
+func (s *webhookAlerter) Alert(res reservation) bool {
+	return s.send(res, "trigger")
+}
+
+func (s *webhookAlerter) Resolve(res reservation) bool {
+	return s.send(res, "resolve")
+}
+
+// send posts the event to every URL at once, a reservation's route target replaces the configured URLs. It counts as
+// sent when any URL took it, so the ones that did aren't posted again on the next check.
+func (s *webhookAlerter) send(res reservation, event string) bool {
+	body, err := s.body(res, event)
+	if err != nil {
+		l.err("Unable to build webhook body for app [%s] component [%s] [%v]", res.App, res.Component, err)
+		return false
+	}
+
+	urls := s.Cfg.Webhook.URL
+	if target := res.alerterTarget(s.Name()); target != "" {
+		urls = strings.Split(target, ",")
+	}
+	deadline := time.Now().Add(webhookRetryBudget)
+	results := make(chan bool, len(urls))
+	posted := 0
+	for _, url := range urls {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		posted++
+		go func(url string) {
+			results <- s.post(url, body, deadline)
+		}(url)
+	}
+	sent := false
+	for i := 0; i < posted; i++ {
+		if <-results {
+			sent = true
+		}
+	}
+	return sent
+}
+
+func (s *webhookAlerter) body(res reservation, event string) ([]byte, error) {
+	payload := webhookPayload{
+		Event:       event,
+		AlertType:   res.AlertType,
+		App:         res.App,
+		Component:   res.Component,
+		Owner:       res.Owner,
+		Notify:      res.Notify,
+		Message:     res.AlertMessage,
+		LastCheckin: res.LastCheckin,
+		Timestamp:   time.Now().UTC().Unix(),
+		Reservation: res,
+	}
+	if s.tmpl == nil {
+		return json.Marshal(payload)
+	}
+	var buf bytes.Buffer
+	if err := s.tmpl.Execute(&buf, payload); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends the body to one URL, retrying failed attempts, nothing runs past the deadline
+func (s *webhookAlerter) post(url string, body []byte, deadline time.Time) bool {
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	for attempt := 0; attempt <= s.Cfg.Webhook.Retries; attempt++ {
+		if attempt > 0 {
+			if time.Now().Add(webhookBackoff).After(deadline) {
+				l.warn("Giving up on webhook [%s] after %d attempts", url, attempt)
+				return false
+			}
+			time.Sleep(webhookBackoff)
+		}
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			l.err("Invalid webhook url [%s] [%v]", url, err)
+			return false
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		for _, header := range s.Cfg.Webhook.Header {
+			if parts := strings.SplitN(header, ":", 2); len(parts) == 2 {
+				req.Header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+			}
+		}
+		if s.Cfg.Webhook.Secret != "" {
+			req.Header.Set("X-Gotel-Signature", "sha256="+sign(body, s.Cfg.Webhook.Secret))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			l.warn("Unable to reach webhook [%s] attempt %d [%v]", url, attempt+1, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			l.info("Webhook sent to [%s]", url)
+			return true
+		}
+		l.warn("Webhook [%s] returned [%s] attempt %d", url, resp.Status, attempt+1)
+	}
+	return false
+}
+
+// sign returns the hex HMAC-SHA256 of the body
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,145 @@
+package gotel
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_webhookAlert(t *testing.T) {
+	oldBackoff := webhookBackoff
+	webhookBackoff = 0
+	defer func() { webhookBackoff = oldBackoff }()
+
+	var (
+		calls     int
+		body      []byte
+		signature string
+		token     string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// fail the first attempt so the retry kicks in
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+		signature = r.Header.Get("X-Gotel-Signature")
+		token = r.Header.Get("X-Token")
+	}))
+	defer srv.Close()
+
+	webhook := &webhookAlerter{}
+	webhook.Cfg.Webhook.URL = []string{srv.URL}
+	webhook.Cfg.Webhook.Header = []string{"X-Token: abc123"}
+	webhook.Cfg.Webhook.Secret = "shh"
+	webhook.Cfg.Webhook.Retries = 1
+	webhook.Bootstrap()
+
+	res := reservation{App: "jimtest", Component: "monitor", AlertType: alertMissedCheckin, AlertMessage: "late"}
+	if !webhook.Alert(res) {
+		t.Fatalf("Expected the webhook to be delivered on retry")
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 attempts got %d", calls)
+	}
+	if signature != "sha256="+sign(body, "shh") || token != "abc123" {
+		t.Fatalf("Unexpected headers signature [%s] token [%s]", signature, token)
+	}
+	payload := webhookPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Unable to decode payload [%v]", err)
+	}
+	if payload.Event != "trigger" || payload.App != "jimtest" || payload.Message != "late" {
+		t.Fatalf("Unexpected payload [%+v]", payload)
+	}
+}
+
+func Test_webhookPartialDelivery(t *testing.T) {
+	oldBackoff := webhookBackoff
+	webhookBackoff = 0
+	defer func() { webhookBackoff = oldBackoff }()
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	webhook := &webhookAlerter{}
+	webhook.Cfg.Webhook.URL = []string{srv.URL + "/up", srv.URL + "/down"}
+	webhook.Cfg.Webhook.Retries = 2
+	webhook.Bootstrap()
+
+	if !webhook.Alert(reservation{App: "jimtest", Component: "monitor"}) {
+		t.Fatalf("Expected the alert to count as sent when one url took it")
+	}
+	if calls["/up"] != 1 || calls["/down"] != 3 {
+		t.Fatalf("Unexpected calls [%v]", calls)
+	}
+}
+
+func Test_webhookRetryBudget(t *testing.T) {
+	oldBackoff, oldBudget := webhookBackoff, webhookRetryBudget
+	webhookBackoff, webhookRetryBudget = 0, 100*time.Millisecond
+	defer func() { webhookBackoff, webhookRetryBudget = oldBackoff, oldBudget }()
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	webhook := &webhookAlerter{}
+	webhook.Cfg.Webhook.URL = []string{srv.URL + "/one", srv.URL + "/two", srv.URL + "/three"}
+	webhook.Cfg.Webhook.Retries = 3
+	webhook.Bootstrap()
+
+	start := time.Now()
+	if webhook.Alert(reservation{App: "jimtest", Component: "monitor"}) {
+		t.Fatalf("Expected the alert to fail against hanging urls")
+	}
+	// every url, first attempt included, shares the one budget
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("Expected the budget to bound the alert, took %v", took)
+	}
+}
+
+func Test_webhookTemplate(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	webhook := &webhookAlerter{}
+	webhook.Cfg.Webhook.URL = []string{"http://unused.invalid"}
+	webhook.Cfg.Webhook.BodyTemplate = `{"text": "{{.App}}/{{.Component}} {{.Event}}", "message": {{json .Message}}}`
+	webhook.Bootstrap()
+
+	// the route target replaces the configured URLs
+	res := reservation{App: "jimtest", Component: "monitor", AlertMessage: "run failed\nexit \"1\"",
+		Alerters: []alerterRoute{{Name: "webhook", Target: srv.URL}}}
+	if !webhook.Resolve(res) {
+		t.Fatalf("Expected the webhook to be delivered")
+	}
+	if string(body) != `{"text": "jimtest/monitor resolve", "message": "run failed\nexit \"1\""}` {
+		t.Fatalf("Unexpected templated body [%s]", body)
+	}
+	decoded := map[string]string{}
+	if err := json.Unmarshal(body, &decoded); err != nil || decoded["message"] != res.AlertMessage {
+		t.Fatalf("Expected valid JSON carrying the message, got [%s] [%v]", body, err)
+	}
+}
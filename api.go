@@ -1,7 +1,6 @@
 package gotel
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +8,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -30,6 +30,9 @@ type node struct {
 
 var validTimeUnits = map[string]int{"seconds": 1, "minutes": 1, "hours": 1}
 
+// maxOutputTail is how much of a job's output is kept with a checkin, anything before it is dropped
+const maxOutputTail = 4096
+
 func writeError(w http.ResponseWriter, e interface{}) {
 	w.WriteHeader(http.StatusBadRequest)
 	w.Header().Set("Content-Type", "application/json")
@@ -77,7 +80,7 @@ func (ge *Endpoint) makeReservation(w http.ResponseWriter, req *http.Request) {
 
 	l.info("%v", res)
 
-	_, err = storeReservation(ge.Db, res)
+	_, err = ge.Store.StoreReservation(res)
 	if err != nil {
 		l.err("Unable to store reservation %v", res)
 		writeError(w, "Unable to store reservation")
@@ -87,52 +90,48 @@ func (ge *Endpoint) makeReservation(w http.ResponseWriter, req *http.Request) {
 }
 
 func (ge *Endpoint) getReservations() ([]reservation, error) {
-	query := "SELECT id, app, component, owner, notify, alert_msg, frequency, time_units, last_checkin_timestamp, num_checkins FROM reservations ORDER BY last_checkin_timestamp DESC"
-	rows, err := ge.Db.Query(query)
+	reservations, err := ge.Store.ListReservations()
 	if err != nil {
 		return nil, err
 	}
-	reservations := []reservation{}
-	defer rows.Close()
-	for rows.Next() {
-		var alertMessage sql.NullString
-		res := reservation{}
-		err = rows.Scan(&res.JobID, &res.App, &res.Component, &res.Owner, &res.Notify, &alertMessage, &res.Frequency,
-			&res.TimeUnits, &res.LastCheckin, &res.NumCheckins)
-		if err != nil {
-			return nil, err
-		}
+	if err = applySnoozes(ge.Store, reservations, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	windows, err := ge.Store.ListMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	for i := range reservations {
+		res := &reservations[i]
 		lastCheckin := time.Unix(res.LastCheckin, 0)
 		res.TimeSinceLastCheckin = RelTime(lastCheckin, time.Now(), "ago", "")
 		res.LastCheckinStr = lastCheckin.Format(time.RFC1123)
-		if FailsSLA(res) {
-			res.FailingSLA = true
-		} else {
-			res.FailingSLA = false
+		status := slaStatus(*res, time.Now().UTC())
+		res.InMaintenance = inMaintenance(windows, *res, time.Now().UTC())
+		res.FailingSLA = status == slaFailing
+		res.Late = status == slaLate
+		if status == slaSnoozed {
+			res.SnoozedStr = time.Unix(res.SnoozedUntil, 0).Format(time.RFC1123)
+		}
+		if res.AckedTimestamp > 0 {
+			res.AckedStr = time.Unix(res.AckedTimestamp, 0).Format(time.RFC1123)
 		}
-		if (!alertMessage.Valid) || (alertMessage.String == "") {
-			res.AlertMessage = alertMessage.String
+		if res.RunStarted > 0 {
+			res.RunningFor = strings.TrimSpace(RelTime(time.Unix(res.RunStarted, 0), time.Now(), "", ""))
 		}
-		reservations = append(reservations, res)
 	}
 	return reservations, nil
 }
 
 func (ge *Endpoint) getNodes() ([]node, error) {
 
-	query := "SELECT id, ip_address, node_id FROM nodes ORDER BY id;"
-	rows, err := ge.Db.Query(query)
+	registered, err := ge.Store.ListNodes()
 	if err != nil {
 		return nil, err
 	}
 	nodes := []node{}
-	defer rows.Close()
-	for rows.Next() {
-		res := node{IsCoordinator: false}
-		err = rows.Scan(&res.ID, &res.IPAddress, &res.NodeID)
-		if err != nil {
-			return nil, err
-		}
+	for _, res := range registered {
+		res.IsCoordinator = false
 
 		resp, err := http.Get(fmt.Sprintf("http://%s:8080/is-coordinator", res.IPAddress))
 		if err != nil {
@@ -160,22 +159,7 @@ func (ge *Endpoint) getNodes() ([]node, error) {
 }
 
 func (ge *Endpoint) getBadGuests() ([]badGuest, error) {
-	query := "SELECT app, component, count(*) AS cnt FROM alerts GROUP BY app, component ORDER by cnt DESC"
-	rows, err := ge.Db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	guests := []badGuest{}
-	defer rows.Close()
-	for rows.Next() {
-		res := badGuest{}
-		err = rows.Scan(&res.App, &res.Component, &res.NumFails)
-		if err != nil {
-			return nil, err
-		}
-		guests = append(guests, res)
-	}
-	return guests, nil
+	return ge.Store.ListBadGuests()
 }
 
 func (ge *Endpoint) listReservations(w http.ResponseWriter, req *http.Request) {
@@ -202,9 +186,28 @@ func (ge *Endpoint) doCheckin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	err = validateCheckin(c)
+	if err != nil {
+		l.warn("Invalid checkin [%v]", c)
+		writeError(w, fmt.Sprintf("Unable to store checkin, validation failure [%v]", err))
+		return
+	}
+	c.Output = tail(c.Output, maxOutputTail)
+
 	now := time.Now().UTC().Unix()
 
-	_, err = storeCheckin(ge.Db, *c, now)
+	// a checkin after /start completes that run, pick up when it started so the run time gets logged
+	if c.Started == 0 {
+		if res, err := ge.Store.GetReservation(c.App, c.Component); err == nil {
+			c.Started = res.RunStarted
+		}
+	}
+
+	if c.failed() {
+		_, err = ge.Store.StoreFailure(*c, now)
+	} else {
+		_, err = ge.Store.StoreCheckin(*c, now)
+	}
 	if err != nil {
 		l.err("Unable to save checkin for %v", c)
 		r := Response{"success": false, "message": "Unable to save checkin: " + c.App}
@@ -212,18 +215,102 @@ func (ge *Endpoint) doCheckin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	_, err = logHouseKeeping(ge.Db, *c, now)
+	_, err = ge.Store.LogHouseKeeping(*c, now)
 	if err != nil {
 		l.err("Unable to save checkin for %v", c)
 		r := Response{"success": false, "message": "Unable to save checkin: " + c.App}
 		writeResponse(w, r)
 		return
 	}
+
+	// the coordinator's job checker alerts on the recorded failure rather than waiting for the SLA to lapse
+	if c.failed() {
+		l.info("app [%s] component [%s] reported a failed run, exit code %d", c.App, c.Component, c.ExitCode)
+		r := Response{"success": true, "message": "Application failure recorded: " + c.App}
+		writeResponse(w, r)
+		return
+	}
 	l.info("app [%s] component [%s] checked in %v", c.App, c.Component, time.Now())
 	r := Response{"success": true, "message": "Application checked in: " + c.App}
 	writeResponse(w, r)
 }
 
+// used when a job kicks off so GoTel can time the run and alert when it goes over max_runtime
+func (ge *Endpoint) doStart(w http.ResponseWriter, req *http.Request) {
+	c := new(checkin)
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&c)
+	if err != nil {
+		l.err("Unable to accept start for %v", c)
+		r := Response{"success": false, "message": "Unable to start: " + c.App}
+		writeResponse(w, r)
+		return
+	}
+
+	now := time.Now().UTC().Unix()
+	ok, err := ge.Store.StoreStart(*c, now)
+	if err != nil {
+		l.err("Unable to save start for %v", c)
+		r := Response{"success": false, "message": "Unable to save start: " + c.App}
+		writeResponse(w, r)
+		return
+	}
+	if !ok {
+		l.warn("Unable to find reservation to start %v", c)
+		r := Response{"success": false, "message": fmt.Sprintf("No reservation for [%s/%s]", c.App, c.Component)}
+		writeResponse(w, r)
+		return
+	}
+	l.info("app [%s] component [%s] started %v", c.App, c.Component, time.Now())
+	r := Response{"success": true, "message": "Application started: " + c.App}
+	writeResponse(w, r)
+}
+
+// used by whoever is dealing with a failing reservation to stop further alerts until it recovers
+func (ge *Endpoint) doAck(w http.ResponseWriter, req *http.Request) {
+	a := new(ack)
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&a)
+	if err != nil {
+		l.err("Unable to accept ack for %v", a)
+		r := Response{"success": false, "message": "Unable to ack: " + a.App}
+		writeResponse(w, r)
+		return
+	}
+	if a.AckedBy == "" {
+		writeError(w, "Unable to store ack, validation failure [acked_by is required]")
+		return
+	}
+
+	res, err := ge.Store.GetReservation(a.App, a.Component)
+	if err != nil {
+		l.warn("Unable to find reservation to ack %v [%v]", a, err)
+		r := Response{"success": false, "message": fmt.Sprintf("No reservation for [%s/%s]", a.App, a.Component)}
+		writeResponse(w, r)
+		return
+	}
+	snoozed := []reservation{res}
+	if err = applySnoozes(ge.Store, snoozed, time.Now().UTC()); err != nil {
+		l.warn("Unable to load snoozes for %v [%v]", a, err)
+	}
+	if !snoozed[0].failing(time.Now().UTC()) {
+		r := Response{"success": false, "message": fmt.Sprintf("[%s/%s] is not failing, nothing to ack", a.App, a.Component)}
+		writeResponse(w, r)
+		return
+	}
+
+	_, err = ge.Store.StoreAck(a, time.Now().UTC().Unix())
+	if err != nil {
+		l.err("Unable to save ack for %v", a)
+		r := Response{"success": false, "message": "Unable to save ack: " + a.App}
+		writeResponse(w, r)
+		return
+	}
+	l.info("app [%s] component [%s] acked by [%s]", a.App, a.Component, a.AckedBy)
+	r := Response{"success": true, "message": fmt.Sprintf("Alerts acknowledged for [%s/%s] until it recovers", a.App, a.Component)}
+	writeResponse(w, r)
+}
+
 // used when you know your service will be offline for a bit and you want to pause alerts
 func (ge *Endpoint) doSnooze(w http.ResponseWriter, req *http.Request) {
 	p := new(snooze)
@@ -243,7 +330,11 @@ func (ge *Endpoint) doSnooze(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	_, err = storeSnooze(ge.Db, p)
+	if p.Start == 0 {
+		p.Start = time.Now().UTC().Unix()
+	}
+	p.End = p.Start + int64(getSecondsFromUnits(p.Duration, p.TimeUnits))
+	_, err = ge.Store.StoreSnooze(p)
 	if err != nil {
 		l.err("Unable to save snooze for %v", p)
 		r := Response{"success": false, "message": "Unable to save snooze: " + p.App}
@@ -251,11 +342,126 @@ func (ge *Endpoint) doSnooze(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	r := Response{"success": true, "message": "Application alerting paused: " + p.App}
+	r := Response{"success": true, "message": "Application alerting paused: " + p.App, "id": p.ID}
 	writeResponse(w, r)
 
 }
 
+// lists the snooze windows that are active or coming up, optionally for one app/component
+func (ge *Endpoint) listSnoozes(w http.ResponseWriter, req *http.Request) {
+	snoozes, err := ge.Store.ListSnoozes(time.Now().UTC().Unix())
+	if err != nil {
+		l.err("Unable to list snoozes [%v]", err)
+		r := Response{"success": false, "message": "Unable to list snoozes"}
+		writeResponse(w, r)
+		return
+	}
+	app, component := req.URL.Query().Get("app"), req.URL.Query().Get("component")
+	result := []snooze{}
+	for _, p := range snoozes {
+		if (app == "" || p.App == app) && (component == "" || p.Component == component) {
+			result = append(result, p)
+		}
+	}
+	writeResponse(w, Response{"success": true, "result": result})
+}
+
+// cancels a snooze window before it ends
+func (ge *Endpoint) cancelSnooze(w http.ResponseWriter, req *http.Request) {
+	p := new(snooze)
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&p)
+	if err != nil {
+		l.err("Unable to accept snooze cancel for %v", p)
+		r := Response{"success": false, "message": "Unable to cancel snooze"}
+		writeResponse(w, r)
+		return
+	}
+
+	cancelled, err := ge.Store.CancelSnooze(p.ID, time.Now().UTC().Unix())
+	if err != nil {
+		l.err("Unable to cancel snooze %d [%v]", p.ID, err)
+		r := Response{"success": false, "message": "Unable to cancel snooze"}
+		writeResponse(w, r)
+		return
+	}
+	if !cancelled {
+		r := Response{"success": false, "message": fmt.Sprintf("No active snooze with id %d", p.ID)}
+		writeResponse(w, r)
+		return
+	}
+	r := Response{"success": true, "message": fmt.Sprintf("Snooze %d cancelled", p.ID)}
+	writeResponse(w, r)
+}
+
+// defines a recurring maintenance window during which alerts are held back
+func (ge *Endpoint) addMaintenance(w http.ResponseWriter, req *http.Request) {
+	m := new(maintenance)
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&m)
+	if err != nil {
+		l.err("Unable to accept maintenance window for %v", m)
+		r := Response{"success": false, "message": "Unable to add maintenance window"}
+		writeResponse(w, r)
+		return
+	}
+
+	err = validateMaintenance(m)
+	if err != nil {
+		l.warn("Invalid maintenance window [%v]", m)
+		writeError(w, fmt.Sprintf("Unable to store maintenance window, validation failure [%v]", err))
+		return
+	}
+
+	_, err = ge.Store.StoreMaintenance(m)
+	if err != nil {
+		l.err("Unable to save maintenance window %v", m)
+		r := Response{"success": false, "message": "Unable to save maintenance window"}
+		writeResponse(w, r)
+		return
+	}
+	r := Response{"success": true, "message": "Maintenance window added", "id": m.ID}
+	writeResponse(w, r)
+}
+
+func (ge *Endpoint) listMaintenance(w http.ResponseWriter, req *http.Request) {
+	windows, err := ge.Store.ListMaintenance()
+	if err != nil {
+		l.err("Unable to list maintenance windows [%v]", err)
+		r := Response{"success": false, "message": "Unable to list maintenance windows"}
+		writeResponse(w, r)
+		return
+	}
+	writeResponse(w, Response{"success": true, "result": windows})
+}
+
+func (ge *Endpoint) removeMaintenance(w http.ResponseWriter, req *http.Request) {
+	m := new(maintenance)
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&m)
+	if err != nil {
+		l.err("Unable to accept maintenance window removal for %v", m)
+		r := Response{"success": false, "message": "Unable to remove maintenance window"}
+		writeResponse(w, r)
+		return
+	}
+
+	removed, err := ge.Store.RemoveMaintenance(m.ID)
+	if err != nil {
+		l.err("Unable to remove maintenance window %d [%v]", m.ID, err)
+		r := Response{"success": false, "message": "Unable to remove maintenance window"}
+		writeResponse(w, r)
+		return
+	}
+	if !removed {
+		r := Response{"success": false, "message": fmt.Sprintf("No maintenance window with id %d", m.ID)}
+		writeResponse(w, r)
+		return
+	}
+	r := Response{"success": true, "message": fmt.Sprintf("Maintenance window %d removed", m.ID)}
+	writeResponse(w, r)
+}
+
 func validateSnooze(snooze *snooze) error {
 	_, ok := validTimeUnits[snooze.TimeUnits]
 	if !ok {
@@ -278,7 +484,7 @@ func (ge *Endpoint) doCheckOut(w http.ResponseWriter, req *http.Request) {
 		writeResponse(w, r)
 		return
 	}
-	_, err = storeCheckOut(ge.Db, p)
+	_, err = ge.Store.StoreCheckOut(p)
 	if err != nil {
 		l.err("Unable to save checkout for %v", p)
 		r := Response{"success": false, "message": "Unable to save checkout: " + p.App}
@@ -293,7 +499,47 @@ func (ge *Endpoint) isCoordinator(w http.ResponseWriter, req *http.Request) {
 	writeResponse(w, coordinator)
 }
 
+func validateCheckin(c *checkin) error {
+	if c.Status != "" && c.Status != checkinSuccess && c.Status != checkinFail {
+		return errors.New("Invalid status passed in, must be success or fail")
+	}
+	return nil
+}
+
+func alerterEnabled(name string) bool {
+	return findAlerter(name) != nil
+}
+
 func validateReservation(res *reservation) error {
+	if res.Grace < 0 {
+		return errors.New("Invalid grace passed in")
+	}
+	if res.MaxRuntime < 0 {
+		return errors.New("Invalid max_runtime passed in")
+	}
+	for _, route := range res.Alerters {
+		if !alerterEnabled(route.Name) {
+			return fmt.Errorf("Invalid alerters passed in, [%s] is not an enabled alerter", route.Name)
+		}
+	}
+	if res.Severity != "" && !validSeverity(res.Severity) {
+		return errors.New("Invalid severity passed in, must be critical, error, warning or info")
+	}
+	if res.EmailSubject != "" {
+		if _, err := parseSubject(res.EmailSubject); err != nil {
+			return fmt.Errorf("Invalid email_subject passed in [%v]", err)
+		}
+	}
+	if _, ok := escalationPolicies[res.Escalation]; res.Escalation != "" && !ok {
+		return fmt.Errorf("Invalid escalation passed in, no policy named [%s]", res.Escalation)
+	}
+	if res.Schedule != "" {
+		_, _, err := parseSchedule(res.Schedule, res.Timezone)
+		if err != nil {
+			return fmt.Errorf("Invalid schedule or timezone passed in [%v]", err)
+		}
+		return nil
+	}
 	timeUnits := map[string]int{"seconds": 1, "minutes": 1, "hours": 1}
 	_, ok := timeUnits[res.TimeUnits]
 	if !ok {
@@ -398,6 +644,14 @@ func (ge *Endpoint) InitAPI(port int, htmlPath string) {
 		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
 		return
 	})
+	http.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			ge.doStart(w, r)
+			return
+		}
+		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
+		return
+	})
 	http.HandleFunc("/checkout", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
 			ge.doCheckOut(w, r)
@@ -406,14 +660,52 @@ func (ge *Endpoint) InitAPI(port int, htmlPath string) {
 		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
 		return
 	})
-	http.HandleFunc("/snooze", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/ack", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
+			ge.doAck(w, r)
+			return
+		}
+		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
+		return
+	})
+	http.HandleFunc("/snooze", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			ge.listSnoozes(w, r)
+			return
+		} else if r.Method == "POST" {
 			ge.doSnooze(w, r)
 			return
 		}
 		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
 		return
 	})
+	http.HandleFunc("/snooze/cancel", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			ge.cancelSnooze(w, r)
+			return
+		}
+		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
+		return
+	})
+	http.HandleFunc("/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			ge.listMaintenance(w, r)
+			return
+		} else if r.Method == "POST" {
+			ge.addMaintenance(w, r)
+			return
+		}
+		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
+		return
+	})
+	http.HandleFunc("/maintenance/remove", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			ge.removeMaintenance(w, r)
+			return
+		}
+		writeError(w, fmt.Sprintf("Invalid method %s", r.Method))
+		return
+	})
 	http.HandleFunc("/is-coordinator", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			ge.isCoordinator(w, r)
@@ -0,0 +1,314 @@
+package gotel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEndpoint(t *testing.T) (*Endpoint, *memStore) {
+	store := newMemStore(Config{})
+	_, err := store.StoreReservation(&reservation{App: "jimtest", Component: "monitor", Owner: "jim@example.com",
+		Notify: "jim@example.com", Frequency: 5, TimeUnits: "minutes"})
+	if err != nil {
+		t.Fatalf("Unable to store reservation [%v]", err)
+	}
+	return &Endpoint{Store: store}, store
+}
+
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder) Response {
+	r := Response{}
+	if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil {
+		t.Fatalf("Unable to decode response [%s] [%v]", w.Body.String(), err)
+	}
+	return r
+}
+
+func Test_doCheckin(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		success     bool
+		numCheckins int
+	}{
+		{"checkin", `{"app": "jimtest", "component": "monitor", "notes": "all is well"}`, true, 1},
+		{"bad json", `{"app": "jimtest", "component": `, false, 0},
+	}
+
+	for _, tt := range tests {
+		ge, store := newTestEndpoint(t)
+		req := httptest.NewRequest("POST", "/checkin", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		ge.doCheckin(w, req)
+
+		r := decodeResponse(t, w)
+		if r["success"] != tt.success {
+			t.Fatalf("%s: expected success [%v] got [%v]", tt.name, tt.success, r)
+		}
+		res, _ := store.GetReservation("jimtest", "monitor")
+		if res.NumCheckins != tt.numCheckins {
+			t.Fatalf("%s: expected %d checkins got %d", tt.name, tt.numCheckins, res.NumCheckins)
+		}
+		if len(store.houseKeeping) != tt.numCheckins {
+			t.Fatalf("%s: expected %d housekeeping entries got %d", tt.name, tt.numCheckins, len(store.houseKeeping))
+		}
+		if tt.success && store.houseKeeping[0].Notes != "all is well" {
+			t.Fatalf("%s: notes were not logged [%v]", tt.name, store.houseKeeping[0])
+		}
+	}
+}
+
+func Test_doSnooze(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		paused time.Duration
+	}{
+		{"snooze", `{"app": "jimtest", "component": "monitor", "duration": 2, "time_units": "hours", "reason": "upgrade", "created_by": "jim"}`, http.StatusOK, 2 * time.Hour},
+		{"bad units", `{"app": "jimtest", "component": "monitor", "duration": 2, "time_units": "fortnights"}`, http.StatusBadRequest, 0},
+		{"no duration", `{"app": "jimtest", "component": "monitor", "time_units": "hours"}`, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		ge, store := newTestEndpoint(t)
+		before, _ := store.GetReservation("jimtest", "monitor")
+
+		req := httptest.NewRequest("POST", "/snooze", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		ge.doSnooze(w, req)
+		if w.Code != tt.status {
+			t.Fatalf("%s: expected status %d got %d", tt.name, tt.status, w.Code)
+		}
+
+		// snoozing keeps the real last checkin and stores a window instead
+		res, _ := store.GetReservation("jimtest", "monitor")
+		if res.LastCheckin != before.LastCheckin {
+			t.Fatalf("%s: last checkin should not have moved", tt.name)
+		}
+		snoozes, _ := store.ListSnoozes(0)
+		if tt.paused == 0 {
+			if len(snoozes) != 0 {
+				t.Fatalf("%s: expected no snooze windows got %v", tt.name, snoozes)
+			}
+			continue
+		}
+		expected := time.Now().Add(tt.paused).UTC().Unix()
+		if len(snoozes) != 1 || snoozes[0].End < expected-5 || snoozes[0].End > expected+5 {
+			t.Fatalf("%s: expected a window ending around %d got %v", tt.name, expected, snoozes)
+		}
+		if snoozes[0].Reason != "upgrade" || snoozes[0].CreatedBy != "jim" {
+			t.Fatalf("%s: reason and creator were not stored [%+v]", tt.name, snoozes[0])
+		}
+	}
+}
+
+func Test_snoozeWindows(t *testing.T) {
+	ge, store := newTestEndpoint(t)
+	now := time.Now().UTC()
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, now.Add(-time.Hour).Unix())
+	active := &snooze{App: "jimtest", Component: "monitor", Start: now.Add(-10 * time.Minute).Unix(), End: now.Add(time.Hour).Unix()}
+	store.StoreSnooze(active)
+	store.StoreSnooze(&snooze{App: "jimtest", Component: "other", Start: now.Add(time.Hour).Unix(), End: now.Add(2 * time.Hour).Unix()})
+
+	req := httptest.NewRequest("GET", "/snooze?component=monitor", nil)
+	w := httptest.NewRecorder()
+	ge.listSnoozes(w, req)
+	if r := decodeResponse(t, w); len(r["result"].([]interface{})) != 1 {
+		t.Fatalf("Expected one snooze for jimtest/monitor [%v]", r)
+	}
+
+	reservations, _ := ge.getReservations()
+	for _, res := range reservations {
+		if res.App == "jimtest" && (res.FailingSLA || res.SnoozedStr == "") {
+			t.Fatalf("Expected jimtest/monitor to be snoozed [%+v]", res)
+		}
+	}
+
+	req = httptest.NewRequest("POST", "/snooze/cancel", strings.NewReader(fmt.Sprintf(`{"id": %d}`, active.ID)))
+	w = httptest.NewRecorder()
+	ge.cancelSnooze(w, req)
+	if r := decodeResponse(t, w); r["success"] != true {
+		t.Fatalf("Expected cancel to succeed [%v]", r)
+	}
+	req = httptest.NewRequest("POST", "/snooze/cancel", strings.NewReader(fmt.Sprintf(`{"id": %d}`, active.ID)))
+	w = httptest.NewRecorder()
+	ge.cancelSnooze(w, req)
+	if r := decodeResponse(t, w); r["success"] != false {
+		t.Fatalf("Expected cancelling twice to fail [%v]", r)
+	}
+
+	reservations, _ = ge.getReservations()
+	for _, res := range reservations {
+		if res.App == "jimtest" && !res.FailingSLA {
+			t.Fatalf("Expected jimtest/monitor to fail once the snooze is cancelled [%+v]", res)
+		}
+	}
+}
+
+func Test_doStart(t *testing.T) {
+	ge, store := newTestEndpoint(t)
+
+	req := httptest.NewRequest("POST", "/start", strings.NewReader(`{"app": "jimtest", "component": "monitor"}`))
+	w := httptest.NewRecorder()
+	ge.doStart(w, req)
+	if r := decodeResponse(t, w); r["success"] != true {
+		t.Fatalf("Expected start to succeed [%v]", r)
+	}
+	res, _ := store.GetReservation("jimtest", "monitor")
+	if res.RunStarted == 0 {
+		t.Fatalf("Run start was not stored")
+	}
+
+	req = httptest.NewRequest("POST", "/start", strings.NewReader(`{"app": "jimtest", "component": "nope"}`))
+	w = httptest.NewRecorder()
+	ge.doStart(w, req)
+	if r := decodeResponse(t, w); r["success"] != false {
+		t.Fatalf("Expected starting an unknown reservation to fail [%v]", r)
+	}
+
+	// pretend the run kicked off a while ago
+	store.StoreStart(checkin{App: "jimtest", Component: "monitor"}, time.Now().UTC().Add(-20*time.Minute).Unix())
+
+	req = httptest.NewRequest("POST", "/checkin", strings.NewReader(`{"app": "jimtest", "component": "monitor"}`))
+	w = httptest.NewRecorder()
+	ge.doCheckin(w, req)
+	if r := decodeResponse(t, w); r["success"] != true {
+		t.Fatalf("Expected checkin to succeed [%v]", r)
+	}
+
+	res, _ = store.GetReservation("jimtest", "monitor")
+	if res.RunStarted != 0 {
+		t.Fatalf("Checkin should end the run in progress")
+	}
+	if len(store.houseKeeping) != 1 {
+		t.Fatalf("Expected one housekeeping entry got %d", len(store.houseKeeping))
+	}
+	if runtime := store.houseKeeping[0].Runtime; runtime < 20*60 || runtime > 20*60+5 {
+		t.Fatalf("Expected a run time of about 20 minutes got %d seconds", runtime)
+	}
+}
+
+func Test_doFailedCheckin(t *testing.T) {
+	ge, store := newTestEndpoint(t)
+	test := setupMonitoring(t, true)
+
+	body := `{"app": "jimtest", "component": "monitor", "status": "fail", "exit_code": 2, "output": "` +
+		strings.Repeat("x", maxOutputTail) + `disk full"}`
+	req := httptest.NewRequest("POST", "/checkin", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	ge.doCheckin(w, req)
+	if r := decodeResponse(t, w); r["success"] != true {
+		t.Fatalf("Expected failed checkin to be recorded [%v]", r)
+	}
+
+	res, _ := store.GetReservation("jimtest", "monitor")
+	if res.NumCheckins != 0 || res.LastStatus != checkinFail || res.LastExitCode != 2 {
+		t.Fatalf("Failure should be recorded without counting as a checkin [%+v]", res)
+	}
+	if len(res.LastOutput) != maxOutputTail || !strings.HasSuffix(res.LastOutput, "disk full") {
+		t.Fatalf("Expected the last %d bytes of output got %d", maxOutputTail, len(res.LastOutput))
+	}
+	if len(store.houseKeeping) != 1 || store.houseKeeping[0].Status != checkinFail {
+		t.Fatalf("Failure was not logged [%v]", store.houseKeeping)
+	}
+	if len(test.alerts) != 0 {
+		t.Fatalf("Expected the checkin to leave alerting to the job checker got %v", test.alerts)
+	}
+
+	// a snoozed job doesn't page on its failure
+	now := time.Now().UTC()
+	store.StoreCheckin(checkin{App: "gotel", Component: "coordinator"}, now.Unix())
+	snoozed := &snooze{App: "jimtest", Component: "monitor", Start: now.Add(-time.Minute).Unix(), End: now.Add(time.Hour).Unix()}
+	store.StoreSnooze(snoozed)
+	jobChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("Expected no alert while snoozed got %v", test.alerts)
+	}
+
+	store.CancelSnooze(snoozed.ID, now.Unix())
+	jobChecker(store)
+	if len(test.alerts) != 1 || test.alerts[0].AlertType != alertJobFailed {
+		t.Fatalf("Expected one %s alert got %v", alertJobFailed, test.alerts)
+	}
+	if !strings.Contains(test.alerts[0].AlertMessage, "exit code 2") {
+		t.Fatalf("Alert message is missing the exit code [%s]", test.alerts[0].AlertMessage)
+	}
+
+	req = httptest.NewRequest("POST", "/checkin", strings.NewReader(`{"app": "jimtest", "component": "monitor", "status": "maybe"}`))
+	w = httptest.NewRecorder()
+	ge.doCheckin(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected an invalid status to be rejected got %d", w.Code)
+	}
+}
+
+func Test_validateReservationAlerters(t *testing.T) {
+	setupMonitoring(t, true)
+	tests := []struct {
+		name   string
+		routes []alerterRoute
+		valid  bool
+	}{
+		{"no routes", nil, true},
+		{"enabled alerter", []alerterRoute{{Name: "TEST"}}, true},
+		{"unknown alerter", []alerterRoute{{Name: "carrier-pigeon"}}, false},
+	}
+
+	for _, tt := range tests {
+		res := &reservation{App: "jimtest", Component: "monitor", Frequency: 5, TimeUnits: "minutes", Alerters: tt.routes}
+		if err := validateReservation(res); (err == nil) != tt.valid {
+			t.Fatalf("%s: expected valid %v got [%v]", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func Test_doAck(t *testing.T) {
+	ge, store := newTestEndpoint(t)
+	test := setupMonitoring(t, true)
+	store.StoreCheckin(checkin{App: "gotel", Component: "coordinator"}, time.Now().UTC().Unix())
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, time.Now().UTC().Unix())
+
+	ackBody := `{"app": "jimtest", "component": "monitor", "acked_by": "jim"}`
+	req := httptest.NewRequest("POST", "/ack", strings.NewReader(ackBody))
+	w := httptest.NewRecorder()
+	ge.doAck(w, req)
+	if r := decodeResponse(t, w); r["success"] != false {
+		t.Fatalf("Expected acking a healthy reservation to fail [%v]", r)
+	}
+
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1403253684)
+	req = httptest.NewRequest("POST", "/ack", strings.NewReader(ackBody))
+	w = httptest.NewRecorder()
+	ge.doAck(w, req)
+	if r := decodeResponse(t, w); r["success"] != true {
+		t.Fatalf("Expected ack to succeed [%v]", r)
+	}
+
+	jobChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("Expected no alerts while acked got %d", len(test.alerts))
+	}
+	reservations, _ := ge.getReservations()
+	for _, res := range reservations {
+		if res.App == "jimtest" && (res.AckedBy != "jim" || res.AckedStr == "") {
+			t.Fatalf("Expected the ack to be listed [%+v]", res)
+		}
+	}
+
+	// recovering clears the ack so the next failure alerts again
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, time.Now().UTC().Unix())
+	jobChecker(store)
+	if res, _ := store.GetReservation("jimtest", "monitor"); res.AckedBy != "" {
+		t.Fatalf("Expected the ack to be cleared after recovering")
+	}
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1403253684)
+	jobChecker(store)
+	if len(test.alerts) != 1 {
+		t.Fatalf("Expected the next failure to alert got %d", len(test.alerts))
+	}
+}
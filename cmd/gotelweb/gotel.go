@@ -7,17 +7,22 @@ import (
 	"github.com/ParsePlatform/go.flagenv"
 
 	"log"
+	"os"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 
 	"time"
 )
 
 func main() {
 
+	dbDriver := flag.String("GOTEL_DB_DRIVER", "mysql", "DB driver to use, mysql, postgres, sqlite3 or memory")
 	dbHost := flag.String("GOTEL_DB_HOST", "127.0.0.1", "Host of the DB instance")
 	dbUser := flag.String("GOTEL_DB_USER", "root", "DB User")
 	dbPass := flag.String("GOTEL_DB_PASSWORD", "", "DB Pass")
+	dbPath := flag.String("GOTEL_DB_PATH", "./gotel.db", "Path of the DB file when using sqlite3")
 	confPath := flag.String("GOTEL_CONFIG_PATH", "./gotel.gcfg", "config file path")
 	sysLogEnabled := flag.Bool("GOTEL_SYSLOG", false, "Use syslog for output logging")
 	htmlPath := flag.String("GOTEL_HTML_PATH", "../..", "Path to the public folder for storing HTML files")
@@ -25,19 +30,37 @@ func main() {
 	flagenv.Parse()
 
 	config := gotel.NewConfig(*confPath, *sysLogEnabled)
-	db := gotel.InitDb(*dbHost, *dbUser, *dbPass, config)
-	defer db.Close()
+	dbConfig := gotel.DbConfig{
+		Driver: *dbDriver,
+		Host:   *dbHost,
+		User:   *dbUser,
+		Pass:   *dbPass,
+		Path:   *dbPath,
+	}
 
-	ge := &gotel.Endpoint{Db: db}
+	// gotelweb migrate status|up [n]|down [n] manages the schema by hand instead of starting the service
+	if flag.Arg(0) == "migrate" {
+		store := gotel.OpenDb(dbConfig)
+		defer store.Close()
+		if err := gotel.Migrate(store, flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	store := gotel.InitDb(dbConfig, config)
+	defer store.Close()
+
+	ge := &gotel.Endpoint{Store: store}
 
-	gotel.InitializeMonitoring(config, db)
+	gotel.InitializeMonitoring(config, store)
 
 	// set up a ticker that every n seconds we check the jobs that should have checked in
 	ticker := time.NewTicker(30 * time.Second)
 	go func() {
 		for t := range ticker.C {
 			log.Println("Running job checker at ", t)
-			gotel.Monitor(ge.Db)
+			gotel.Monitor(ge.Store)
 		}
 	}()
 
@@ -12,15 +12,91 @@ type Config struct {
 		GotelOwnerEmail    string
 		HoursBetweenAlerts int64
 		DaysToStoreLogs    int
+		// BaseURL is where GoTel's pages are reached for links in alerts, http://<this node's ip>:8080 by default
+		BaseURL string
 	}
 	SMTP struct {
 		Enabled     bool
 		FromAddress string
 		ReplyTO     string
+		// TLS is starttls to require STARTTLS, tls for implicit TLS (port 465) or none, STARTTLS is used when offered by default
+		TLS                string
+		CAFile             string // PEM bundle to verify the server with instead of the system roots
+		InsecureSkipVerify bool
+		NoAuth             bool // skip auth, for internal relays
+		// Subject is a template for the email subject, TextTemplate and HTMLTemplate are paths to templates for the
+		// two parts of the email. They're given an emailData.
+		Subject      string
+		TextTemplate string
+		HTMLTemplate string
 	}
 	PagerDuty struct {
 		Enabled    bool
 		ServiceKey string
+		// Version 2 uses the Events API v2, ServiceKey is then an integration routing key
+		Version  int
+		Severity string // Events API v2 severity for reservations without one, defaults to critical
+	}
+	// Opsgenie creates alerts through the alert API, closing them on recovery
+	Opsgenie struct {
+		Enabled  bool
+		APIKey   string
+		APIURL   string // defaults to https://api.opsgenie.com, use https://api.eu.opsgenie.com for the EU instance
+		Priority string // P1-P5, defaults to P3
+	}
+	// Teams posts connector cards to an incoming webhook, a reservation's route target is another webhook url
+	Teams struct {
+		Enabled    bool
+		WebhookURL string
+	}
+	// Mattermost posts to a Mattermost compatible incoming webhook, a reservation's route target picks the channel
+	Mattermost struct {
+		Enabled    bool
+		WebhookURL string
+		Channel    string
+		Username   string
+		IconURL    string
+	}
+	// Webhook POSTs alerts as JSON to one or more URLs
+	Webhook struct {
+		Enabled bool
+		URL     []string
+		// BodyTemplate is an optional text/template for the request body, it's given a webhookPayload
+		BodyTemplate string
+		// Header lines are sent with every request, "Name: value"
+		Header []string
+		// Secret signs the body with HMAC-SHA256 in the X-Gotel-Signature header when set
+		Secret         string
+		TimeoutSeconds int
+		Retries        int
+	}
+	// Slack posts alerts to an incoming webhook, a reservation's route target picks the channel
+	Slack struct {
+		Enabled    bool
+		WebhookURL string
+		Channel    string // default channel, the webhook's own channel when empty
+		Username   string
+		IconEmoji  string
+	}
+	// Escalation holds the named escalation policies reservations can use. Each level is "<after> <alerter> [target]",
+	// e.g. "30m PagerDuty", fired once the reservation has been failing for that long
+	Escalation map[string]*struct {
+		Level []string
+	}
+	// Digest batches an alerter's failures, e.g. [digest "SMTP"] window = 5m sends everything that fails within five
+	// minutes of the first failure to each recipient as one message
+	Digest map[string]*struct {
+		Window string
+	}
+	// Regression compares recent run times against each component's own history
+	Regression struct {
+		Enabled bool
+		// RecentRuns is how many of the latest runs the median is taken over, defaults to 5
+		RecentRuns int
+		// BaselineDays is how far back the p95 baseline looks, defaults to 30
+		BaselineDays int
+		// MinBaselineRuns is how many older runs are needed before a baseline is trusted, defaults to 10
+		MinBaselineRuns int
 	}
 }
 
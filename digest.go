@@ -0,0 +1,217 @@
+package gotel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// digester is implemented by alerters that can send many failing reservations to a recipient as one message
+type digester interface {
+	// Recipients returns who the alerter would send the reservation's alert to
+	Recipients(res reservation) []string
+	// Digest sends every recipient one message listing their failing reservations, returning who got theirs
+	Digest(digests []digest) map[string]bool
+}
+
+// digest is what one recipient is sent when an alerter's digests go out
+type digest struct {
+	recipient string
+	failures  []reservation
+}
+
+// pendingDigest holds an alerter's failures until its window, which starts with the first failure, has passed
+type pendingDigest struct {
+	started  time.Time
+	failures []pendingFailure
+}
+
+// pendingFailure is a failure waiting for a digest. recipients is nil until a digest fails to reach some of them,
+// then it's who is left to tell, and stored is set once anyone was told and the alert was stored.
+type pendingFailure struct {
+	res        reservation
+	recipients []string
+	stored     bool
+}
+
+var (
+	// digestWindows are parsed from the [digest "alerter"] config sections, by alerter name
+	digestWindows = map[string]time.Duration{}
+
+	// pendingDigests only live on the coordinator. Nothing is stored as alerted until its digest goes out, so after a
+	// restart or failover the new coordinator's job checks find the reservations still failing and queue them again,
+	// in a digest whose window starts over.
+	digestMu       sync.Mutex
+	pendingDigests = map[string]*pendingDigest{}
+)
+
+// parseDigests builds the digest windows from the config, every alerter has to be enabled and support digests
+func parseDigests(c Config) (map[string]time.Duration, error) {
+	windows := make(map[string]time.Duration)
+	for name, digest := range c.Digest {
+		alerter := findAlerter(name)
+		if alerter == nil {
+			return nil, fmt.Errorf("digest [%s] is not an enabled alerter", name)
+		}
+		if _, ok := alerter.(digester); !ok {
+			return nil, fmt.Errorf("digest [%s] alerter doesn't support digests", name)
+		}
+		if digest == nil {
+			return nil, fmt.Errorf("digest [%s] has no window", name)
+		}
+		window, err := time.ParseDuration(digest.Window)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("digest [%s] has an invalid window [%s]", name, digest.Window)
+		}
+		windows[alerter.Name()] = window
+	}
+	return windows, nil
+}
+
+// queueDigest holds the reservation's alert for the alerter's next digest, false when the alerter has no digest
+// window. A reservation already waiting is replaced so the digest has its latest state.
+func queueDigest(a alerter, res reservation) bool {
+	if _, ok := a.(digester); !ok {
+		return false
+	}
+	if _, ok := digestWindows[a.Name()]; !ok {
+		return false
+	}
+
+	digestMu.Lock()
+	defer digestMu.Unlock()
+	pending, ok := pendingDigests[a.Name()]
+	if !ok {
+		pending = &pendingDigest{started: time.Now().UTC()}
+		pendingDigests[a.Name()] = pending
+	}
+	for i := range pending.failures {
+		if pending.failures[i].res.mapKey("") == res.mapKey("") {
+			pending.failures[i].res = res
+			return true
+		}
+	}
+	l.info("Holding %s alert for [%s/%s/%s] for the next digest", res.AlertType, res.App, res.Component, a.Name())
+	pending.failures = append(pending.failures, pendingFailure{res: res})
+	return true
+}
+
+// dropDigest takes a recovered reservation's alert type out of the digests that haven't gone out yet
+func dropDigest(res reservation) {
+	digestMu.Lock()
+	defer digestMu.Unlock()
+	for _, pending := range pendingDigests {
+		failures := pending.failures[:0]
+		for _, f := range pending.failures {
+			if f.res.mapKey("") != res.mapKey("") {
+				failures = append(failures, f)
+			}
+		}
+		pending.failures = failures
+	}
+}
+
+// flushDigests sends the digests whose window has passed, grouped per recipient. An alert is stored once any
+// recipient got its digest, same as alerts sent one by one, otherwise the next job check queues it again. Recipients
+// whose digest didn't go out are held for the alerter's next digest.
+func flushDigests(store Store, now time.Time) {
+	due := make(map[string][]pendingFailure)
+	digestMu.Lock()
+	for name, pending := range pendingDigests {
+		if now.Sub(pending.started) >= digestWindows[name] {
+			due[name] = pending.failures
+			delete(pendingDigests, name)
+		}
+	}
+	digestMu.Unlock()
+
+	for name, failures := range due {
+		alerter := findAlerter(name)
+		if alerter == nil || len(failures) == 0 {
+			continue
+		}
+		d := alerter.(digester)
+
+		recipients := []string{}
+		groups := make(map[string][]reservation)
+		for i, f := range failures {
+			if f.recipients == nil {
+				failures[i].recipients = d.Recipients(f.res)
+			}
+			if len(failures[i].recipients) == 0 {
+				l.warn("No %s digest recipients for [%s/%s]", name, f.res.App, f.res.Component)
+			}
+			for _, recipient := range failures[i].recipients {
+				if _, ok := groups[recipient]; !ok {
+					recipients = append(recipients, recipient)
+				}
+				groups[recipient] = append(groups[recipient], f.res)
+			}
+		}
+
+		digests := []digest{}
+		for _, recipient := range recipients {
+			l.info("Sending %s digest of %d failures to [%s]", name, len(groups[recipient]), recipient)
+			digests = append(digests, digest{recipient: recipient, failures: groups[recipient]})
+		}
+		delivered := d.Digest(digests)
+
+		// failures without recipients are left for the next job check to queue again
+		retry := []pendingFailure{}
+		for _, f := range failures {
+			missed := []string{}
+			for _, recipient := range f.recipients {
+				if !delivered[recipient] {
+					missed = append(missed, recipient)
+				}
+			}
+			if len(missed) < len(f.recipients) && !f.stored {
+				if err := store.StoreAlert(f.res, []string{name}); err != nil {
+					l.err("Unable to store alert [%v]", err)
+				}
+				f.stored = true
+			}
+			if len(missed) > 0 {
+				f.recipients = missed
+				retry = append(retry, f)
+			}
+		}
+		if len(retry) > 0 {
+			requeueDigest(name, retry, now)
+		}
+	}
+}
+
+// requeueDigest holds failures whose digest didn't reach everyone for the alerter's next digest, unless the
+// failure was queued again in the meantime
+func requeueDigest(name string, retry []pendingFailure, now time.Time) {
+	digestMu.Lock()
+	defer digestMu.Unlock()
+	pending, ok := pendingDigests[name]
+	if !ok {
+		pending = &pendingDigest{started: now}
+		pendingDigests[name] = pending
+	}
+	queued := make(map[string]bool)
+	for _, f := range pending.failures {
+		queued[f.res.mapKey("")] = true
+	}
+	for _, f := range retry {
+		if !queued[f.res.mapKey("")] {
+			l.info("Holding %s digest for [%s/%s] for %d recipients", name, f.res.App, f.res.Component, len(f.recipients))
+			pending.failures = append(pending.failures, f)
+		}
+	}
+}
+
+// splitRecipients splits a comma-separated list, dropping empty entries
+func splitRecipients(list string) []string {
+	recipients := []string{}
+	for _, r := range strings.Split(list, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			recipients = append(recipients, r)
+		}
+	}
+	return recipients
+}
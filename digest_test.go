@@ -0,0 +1,156 @@
+package gotel
+
+import (
+	"testing"
+	"time"
+)
+
+// digestAlerter is a test alerter that takes digests, sending to each reservation's notify list except the down ones
+type digestAlerter struct {
+	testAlerter
+	digests map[string][]reservation
+	down    map[string]bool
+}
+
+func (a *digestAlerter) Recipients(res reservation) []string {
+	return splitRecipients(res.Notify)
+}
+
+func (a *digestAlerter) Digest(digests []digest) map[string]bool {
+	delivered := make(map[string]bool)
+	for _, d := range digests {
+		if !a.down[d.recipient] {
+			a.digests[d.recipient] = append(a.digests[d.recipient], d.failures...)
+			delivered[d.recipient] = true
+		}
+	}
+	return delivered
+}
+
+func Test_digests(t *testing.T) {
+	setupMonitoring(t, true)
+	test := &digestAlerter{digests: map[string][]reservation{}}
+	alertFuncs = []alerter{test}
+	oldWindows := digestWindows
+	t.Cleanup(func() {
+		digestWindows = oldWindows
+		pendingDigests = map[string]*pendingDigest{}
+	})
+
+	c := Config{}
+	c.Digest = map[string]*struct{ Window string }{"TEST": {Window: "5m"}}
+	windows, err := parseDigests(c)
+	if err != nil || windows["test"] != 5*time.Minute {
+		t.Fatalf("Unexpected digest windows [%v] [%v]", windows, err)
+	}
+	digestWindows = windows
+
+	store := newMemStore(Config{})
+	failing := []reservation{
+		{App: "billing", Component: "import", Notify: "jim@example.com, ops@example.com"},
+		{App: "billing", Component: "export", Notify: "ops@example.com"},
+		{App: "search", Component: "index", Notify: "ops@example.com"},
+	}
+	// nobody to send billing/reports to, so it's never stored as sent
+	failing = append(failing, reservation{App: "billing", Component: "reports"})
+	for _, res := range failing {
+		sendAlerts(store, res, alertMissedCheckin, defaultAlertMessage)
+	}
+	// search/index recovers before the digest goes out
+	resolveAlerts(store, failing[2], alertMissedCheckin)
+
+	now := time.Now().UTC()
+	flushDigests(store, now)
+	if len(test.alerts) != 0 || len(test.digests) != 0 {
+		t.Fatalf("Expected nothing sent within the window got alerts [%v] digests [%v]", test.alerts, test.digests)
+	}
+
+	flushDigests(store, now.Add(5*time.Minute))
+	if len(test.alerts) != 0 {
+		t.Fatalf("Expected no individual alerts got %d", len(test.alerts))
+	}
+	if len(test.digests["jim@example.com"]) != 1 || len(test.digests["ops@example.com"]) != 2 {
+		t.Fatalf("Unexpected digests [%v]", test.digests)
+	}
+
+	// delivered digests count as sent alerts, so the next job check doesn't queue them again
+	res := failing[0]
+	res.AlertType = alertMissedCheckin
+	if !alreadySentRecently(store, res, test.Name()) {
+		t.Fatalf("Expected the digested alert to be stored")
+	}
+	res = failing[3]
+	res.AlertType = alertMissedCheckin
+	if alreadySentRecently(store, res, test.Name()) {
+		t.Fatalf("Expected a digest without recipients not to be stored")
+	}
+	sendAlerts(store, failing[0], alertMissedCheckin, defaultAlertMessage)
+	if len(pendingDigests) != 0 {
+		t.Fatalf("Expected nothing queued for an alert that was already sent [%v]", pendingDigests)
+	}
+}
+
+func Test_digestRetriesRecipients(t *testing.T) {
+	setupMonitoring(t, true)
+	test := &digestAlerter{digests: map[string][]reservation{}, down: map[string]bool{"ops@example.com": true}}
+	alertFuncs = []alerter{test}
+	oldWindows := digestWindows
+	t.Cleanup(func() {
+		digestWindows = oldWindows
+		pendingDigests = map[string]*pendingDigest{}
+	})
+	digestWindows = map[string]time.Duration{test.Name(): 5 * time.Minute}
+
+	store := newMemStore(Config{})
+	res := reservation{App: "billing", Component: "import", Notify: "jim@example.com, ops@example.com"}
+	sendAlerts(store, res, alertMissedCheckin, defaultAlertMessage)
+
+	now := time.Now().UTC()
+	flushDigests(store, now.Add(5*time.Minute))
+	if len(test.digests["jim@example.com"]) != 1 || len(test.digests["ops@example.com"]) != 0 {
+		t.Fatalf("Unexpected digests [%v]", test.digests)
+	}
+	res.AlertType = alertMissedCheckin
+	if !alreadySentRecently(store, res, test.Name()) {
+		t.Fatalf("Expected the alert to be stored once jim got it")
+	}
+
+	// ops is held for the next digest, and jim isn't sent it again
+	delete(test.down, "ops@example.com")
+	flushDigests(store, now.Add(9*time.Minute))
+	if len(test.digests["ops@example.com"]) != 0 {
+		t.Fatalf("Expected the retry to wait for the next window [%v]", test.digests)
+	}
+	flushDigests(store, now.Add(10*time.Minute))
+	if len(test.digests["jim@example.com"]) != 1 || len(test.digests["ops@example.com"]) != 1 {
+		t.Fatalf("Unexpected digests after the retry [%v]", test.digests)
+	}
+	if len(pendingDigests) != 0 {
+		t.Fatalf("Expected nothing left pending [%v]", pendingDigests)
+	}
+}
+
+func Test_parseDigests(t *testing.T) {
+	setupMonitoring(t, true)
+
+	tests := []struct {
+		name    string
+		alerter alerter
+		digest  string
+		window  string
+	}{
+		{"unknown alerter", &digestAlerter{}, "carrier-pigeon", "5m"},
+		{"no digest support", &testAlerter{}, "test", "5m"},
+		{"bad window", &digestAlerter{}, "test", "soon"},
+		{"no window", &digestAlerter{}, "test", "0s"},
+	}
+
+	for _, tt := range tests {
+		alertFuncs = []alerter{tt.alerter}
+		c := Config{}
+		c.Digest = map[string]*struct{ Window string }{tt.digest: {Window: tt.window}}
+		if _, err := parseDigests(c); err == nil {
+			t.Fatalf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package gotel
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// emailHistory is how many of the latest checkins alert emails list
+const emailHistory = 5
+
+const (
+	defaultEmailSubject = "{{.Event}}: {{.Reservation.App}}/{{.Reservation.Component}}"
+
+	defaultEmailText = `{{.Message}}
+
+Alert time is [{{.AlertTime}}]
+
+Last checkin [{{.LastCheckin}}] {{.Since}}
+Owner [{{.Reservation.Owner}}]
+{{- if .History}}
+
+Recent checkins:
+{{- range .History}}
+  {{unix .LastCheckin}} {{.Status}}{{if .Runtime}} in {{.Runtime}}s{{end}}{{if .Notes}} - {{.Notes}}{{end}}
+{{- end}}
+{{- end}}
+
+Notification list [{{.Notify}}]
+
+{{.StatusURL}}
+`
+
+	defaultEmailHTML = `<html>
+<body style="font-family: sans-serif">
+<h2 style="color: {{if .Resolved}}#2EB886{{else}}#D9534F{{end}}">{{.Event}}</h2>
+<p>{{.Message}}</p>
+<table cellpadding="4">
+<tr><th align="left">App</th><td>{{.Reservation.App}}</td></tr>
+<tr><th align="left">Component</th><td>{{.Reservation.Component}}</td></tr>
+<tr><th align="left">Owner</th><td>{{.Reservation.Owner}}</td></tr>
+<tr><th align="left">Last Checkin</th><td>{{.LastCheckin}} ({{.Since}})</td></tr>
+<tr><th align="left">Alert Time</th><td>{{.AlertTime}}</td></tr>
+</table>
+{{- if .History}}
+<h3>Recent checkins</h3>
+<table cellpadding="4" border="1" style="border-collapse: collapse">
+<tr><th>Checkin</th><th>Status</th><th>Runtime</th><th>Notes</th></tr>
+{{- range .History}}
+<tr><td>{{unix .LastCheckin}}</td><td>{{.Status}}</td><td>{{if .Runtime}}{{.Runtime}}s{{end}}</td><td>{{.Notes}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<p>Notification list [{{.Notify}}]</p>
+<p><a href="{{.StatusURL}}">View {{.Reservation.App}}/{{.Reservation.Component}} on GoTel</a></p>
+</body>
+</html>
+`
+
+	digestEmailText = `{{len .Failures}} jobs are failing
+
+{{range .Failures -}}
+{{.Reservation.App}}/{{.Reservation.Component}} {{.Reservation.AlertType}}, last checkin {{.Since}}. Owner [{{.Reservation.Owner}}]
+  {{.StatusURL}}
+{{end}}
+Alert time is [{{.AlertTime}}]
+`
+
+	digestEmailHTML = `<html>
+<body style="font-family: sans-serif">
+<h2 style="color: #D9534F">{{len .Failures}} jobs are failing</h2>
+<table cellpadding="4" border="1" style="border-collapse: collapse">
+<tr><th>App/Component</th><th>Alert</th><th>Last Checkin</th><th>Owner</th></tr>
+{{- range .Failures}}
+<tr><td><a href="{{.StatusURL}}">{{.Reservation.App}}/{{.Reservation.Component}}</a></td><td>{{.Reservation.AlertType}}</td><td>{{.Since}}</td><td>{{.Reservation.Owner}}</td></tr>
+{{- end}}
+</table>
+<p>Alert time is [{{.AlertTime}}]</p>
+</body>
+</html>
+`
+)
+
+// emailData is what the subject, text and HTML email templates are executed with
+type emailData struct {
+	Event       string // the alert's title, see alertTitle, or Job Recovered
+	Resolved    bool
+	Message     string // the reservation's formatted alert message
+	Reservation reservation
+	LastCheckin string
+	Since       string
+	AlertTime   string
+	Notify      string
+	StatusURL   string              // the reservation on the /status page
+	History     []houseKeepingEntry // the latest checkins, newest first
+}
+
+var emailFuncs = map[string]interface{}{
+	"unix": func(ts int64) string { return time.Unix(ts, 0).UTC().Format(time.RFC1123) },
+}
+
+// digestData is what the digest email templates are executed with
+type digestData struct {
+	Failures  []emailData
+	AlertTime string
+}
+
+// emailTemplates holds the parsed email templates
+type emailTemplates struct {
+	subject    *template.Template
+	text       *template.Template
+	html       *htmltemplate.Template
+	digestText *template.Template
+	digestHTML *htmltemplate.Template
+}
+
+// parseEmailTemplates parses the configured templates, falling back to the defaults. The text and HTML templates
+// are file paths, the subject is the template itself.
+func parseEmailTemplates(c Config) (*emailTemplates, error) {
+	subject, text, html := defaultEmailSubject, defaultEmailText, defaultEmailHTML
+	if c.SMTP.Subject != "" {
+		subject = c.SMTP.Subject
+	}
+	if c.SMTP.TextTemplate != "" {
+		b, err := ioutil.ReadFile(c.SMTP.TextTemplate)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+	if c.SMTP.HTMLTemplate != "" {
+		b, err := ioutil.ReadFile(c.SMTP.HTMLTemplate)
+		if err != nil {
+			return nil, err
+		}
+		html = string(b)
+	}
+
+	t := &emailTemplates{}
+	var err error
+	if t.subject, err = parseSubject(subject); err != nil {
+		return nil, fmt.Errorf("invalid subject [%v]", err)
+	}
+	if t.text, err = template.New("text").Funcs(emailFuncs).Parse(text); err != nil {
+		return nil, fmt.Errorf("invalid text template [%v]", err)
+	}
+	if t.html, err = htmltemplate.New("html").Funcs(emailFuncs).Parse(html); err != nil {
+		return nil, fmt.Errorf("invalid html template [%v]", err)
+	}
+	t.digestText = template.Must(template.New("digest_text").Parse(digestEmailText))
+	t.digestHTML = htmltemplate.Must(htmltemplate.New("digest_html").Parse(digestEmailHTML))
+	return t, nil
+}
+
+func parseSubject(subject string) (*template.Template, error) {
+	return template.New("subject").Funcs(emailFuncs).Parse(subject)
+}
+
+// newEmailData gathers what the templates need, the history comes from the store when there is one
+func newEmailData(store Store, res reservation, event string, resolved bool, notify, baseURL string) emailData {
+	data := emailData{
+		Event:       event,
+		Resolved:    resolved,
+		Message:     res.AlertMessage,
+		Reservation: res,
+		LastCheckin: res.formatAlert("{last}"),
+		Since:       res.formatAlert("{since}"),
+		AlertTime:   time.Now().Format(time.RFC822), // in case email delivery delay, let them know the actual date
+		Notify:      notify,
+		StatusURL:   fmt.Sprintf("%s/status#res-%d", strings.TrimSuffix(baseURL, "/"), res.JobID),
+	}
+	if store != nil {
+		history, err := store.ListHouseKeeping(res.App, res.Component, emailHistory)
+		if err != nil {
+			l.warn("Unable to load checkin history for [%s/%s] [%v]", res.App, res.Component, err)
+		}
+		data.History = history
+	}
+	return data
+}
+
+// render builds a multipart/alternative email with text and HTML parts. A reservation's email_subject replaces
+// the configured subject.
+func (t *emailTemplates) render(data emailData, from, replyTo, to string) ([]byte, error) {
+	subjectTmpl := t.subject
+	if data.Reservation.EmailSubject != "" {
+		var err error
+		if subjectTmpl, err = parseSubject(data.Reservation.EmailSubject); err != nil {
+			return nil, fmt.Errorf("invalid email_subject [%v]", err)
+		}
+	}
+	var subject, text, html bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	return buildEmail(subject.String(), text.Bytes(), html.Bytes(), from, replyTo, to)
+}
+
+// renderDigest builds one email listing every failure
+func (t *emailTemplates) renderDigest(failures []emailData, from, replyTo, to string) ([]byte, error) {
+	data := digestData{Failures: failures, AlertTime: time.Now().Format(time.RFC822)}
+	var text, html bytes.Buffer
+	if err := t.digestText.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := t.digestHTML.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	return buildEmail(fmt.Sprintf("%d jobs failing", len(failures)), text.Bytes(), html.Bytes(), from, replyTo, to)
+}
+
+// buildEmail puts the text and HTML parts together as a multipart/alternative email
+func buildEmail(subject string, text, html []byte, from, replyTo, to string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	// a subject is one line, whatever the template did
+	subjectLine := strings.Join(strings.Fields(subject), " ")
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "Subject: %s\r\nFrom: %s\r\nReply-to: %s\r\nTo: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+		mime.QEncoding.Encode("utf-8", subjectLine), from, replyTo, to, time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package gotel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func Test_renderEmail(t *testing.T) {
+	store := newMemStore(Config{})
+	store.LogHouseKeeping(checkin{App: "jimtest", Component: "monitor", Notes: "nightly import"}, 1403253684)
+	res := reservation{JobID: 7, App: "jimtest", Component: "monitor", Owner: "jim", AlertMessage: "failed checkin"}
+
+	templates, err := parseEmailTemplates(Config{})
+	if err != nil {
+		t.Fatalf("Unable to parse the default templates [%v]", err)
+	}
+
+	tests := []struct {
+		name         string
+		alertType    string
+		emailSubject string
+		subject      string
+	}{
+		{"default subject", alertMissedCheckin, "", "Job Failed to checkin: jimtest/monitor"},
+		{"max runtime subject", alertMaxRuntime, "", "Job Over Max Runtime: jimtest/monitor"},
+		{"reservation subject", alertJobFailed, "[{{.Reservation.Owner}}] {{.Event}}", "[jim] Job Run Failed"},
+	}
+
+	for _, tt := range tests {
+		res.AlertType = tt.alertType
+		res.EmailSubject = tt.emailSubject
+		data := newEmailData(store, res, alertTitle(res), false, "jim@example.com", "http://gotel:8080/")
+		raw, err := templates.render(data, "gotel@example.com", "ops@example.com", "jim@example.com")
+		if err != nil {
+			t.Fatalf("%s: unable to render [%v]", tt.name, err)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("%s: invalid message [%v]", tt.name, err)
+		}
+		if subject := msg.Header.Get("Subject"); subject != tt.subject {
+			t.Fatalf("%s: expected subject [%s] got [%s]", tt.name, tt.subject, subject)
+		}
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/alternative" {
+			t.Fatalf("%s: expected multipart/alternative got [%s] [%v]", tt.name, mediaType, err)
+		}
+		parts := multipart.NewReader(msg.Body, params["boundary"])
+		types := []string{}
+		for {
+			part, err := parts.NextPart()
+			if err != nil {
+				break
+			}
+			body, _ := ioutil.ReadAll(part)
+			types = append(types, strings.Split(part.Header.Get("Content-Type"), ";")[0])
+			for _, want := range []string{"failed checkin", "nightly import", "http://gotel:8080/status#res-7"} {
+				if !strings.Contains(string(body), want) {
+					t.Fatalf("%s: expected %s part to contain [%s] got [%s]", tt.name, types[len(types)-1], want, body)
+				}
+			}
+		}
+		if strings.Join(types, ",") != "text/plain,text/html" {
+			t.Fatalf("%s: unexpected parts [%v]", tt.name, types)
+		}
+	}
+}
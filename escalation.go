@@ -0,0 +1,126 @@
+package gotel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// escalationLevel fires an alerter once a reservation has been failing for After
+type escalationLevel struct {
+	After   time.Duration
+	Alerter string
+	Target  string // may use the alert placeholders, e.g. {owner}
+}
+
+// escalation is how far a failing reservation has escalated, Level is the index of the last level that fired
+type escalation struct {
+	Started int64
+	Level   int
+}
+
+// escalationPolicies are parsed from the [escalation "name"] config sections
+var escalationPolicies = map[string][]escalationLevel{}
+
+// parseEscalationLevel parses a "<after> <alerter> [target]" config line
+func parseEscalationLevel(line string) (escalationLevel, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return escalationLevel{}, fmt.Errorf("escalation level [%s] should be <after> <alerter> [target]", line)
+	}
+	after, err := time.ParseDuration(fields[0])
+	if err != nil {
+		return escalationLevel{}, fmt.Errorf("escalation level [%s] has an invalid duration [%v]", line, err)
+	}
+	level := escalationLevel{After: after, Alerter: fields[1]}
+	if len(fields) > 2 {
+		level.Target = strings.Join(fields[2:], " ")
+	}
+	return level, nil
+}
+
+// parseEscalations builds the escalation policies from the config, every level has to use an enabled alerter
+func parseEscalations(c Config) (map[string][]escalationLevel, error) {
+	policies := make(map[string][]escalationLevel)
+	for name, policy := range c.Escalation {
+		if policy == nil || len(policy.Level) == 0 {
+			return nil, fmt.Errorf("escalation [%s] has no levels", name)
+		}
+		levels := []escalationLevel{}
+		for _, line := range policy.Level {
+			level, err := parseEscalationLevel(line)
+			if err != nil {
+				return nil, err
+			}
+			if !alerterEnabled(level.Alerter) {
+				return nil, fmt.Errorf("escalation [%s] uses [%s] which is not an enabled alerter", name, level.Alerter)
+			}
+			levels = append(levels, level)
+		}
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].After < levels[j].After })
+		policies[name] = levels
+	}
+	return policies, nil
+}
+
+// escalate fires every level of the reservation's escalation policy that is due and hasn't fired yet. The progress
+// is kept in the store so a new coordinator carries on where the old one left off.
+func escalate(store Store, res reservation, levels []escalationLevel) {
+	state, err := store.GetEscalation(res)
+	if err != nil {
+		l.err("Unable to load escalation for [%s/%s] [%v]", res.App, res.Component, err)
+		return
+	}
+	now := time.Now().UTC()
+	if state.Started == 0 {
+		state = escalation{Started: now.Unix(), Level: -1}
+		if err = store.StoreEscalation(res, state); err != nil {
+			l.err("Unable to store escalation for [%s/%s] [%v]", res.App, res.Component, err)
+			return
+		}
+	}
+
+	failingFor := now.Sub(time.Unix(state.Started, 0))
+	for i := state.Level + 1; i < len(levels) && failingFor >= levels[i].After; i++ {
+		level := levels[i]
+		alerter := findAlerter(level.Alerter)
+		if alerter == nil {
+			l.warn("Escalation [%s] level %d uses [%s] which is not enabled", res.Escalation, i, level.Alerter)
+			continue
+		}
+		routed := res
+		routed.Alerters = []alerterRoute{{Name: alerter.Name(), Target: res.formatAlert(level.Target)}}
+		l.info("Escalating [%s/%s] to level %d [%s] after %v", res.App, res.Component, i, alerter.Name(), failingFor)
+		if !alerter.Alert(routed) {
+			// try the level again on the next job check
+			return
+		}
+		if err = store.StoreAlert(routed, []string{alerter.Name()}); err != nil {
+			l.err("Unable to store alert [%v]", err)
+		}
+		state.Level = i
+		if err = store.StoreEscalation(res, state); err != nil {
+			l.err("Unable to store escalation for [%s/%s] [%v]", res.App, res.Component, err)
+		}
+	}
+}
+
+// escalationRoutes routes a resolve to the targets the levels that fired used, the latest level wins when an
+// alerter was used more than once
+func escalationRoutes(res reservation, levels []escalationLevel) []alerterRoute {
+	routes := []alerterRoute{}
+	for i := len(levels) - 1; i >= 0; i-- {
+		routes = append(routes, alerterRoute{Name: levels[i].Alerter, Target: res.formatAlert(levels[i].Target)})
+	}
+	return routes
+}
+
+func findAlerter(name string) alerter {
+	for _, alerter := range alertFuncs {
+		if strings.EqualFold(alerter.Name(), name) {
+			return alerter
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package gotel
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseEscalationLevel(t *testing.T) {
+	tests := []struct {
+		line  string
+		level escalationLevel
+		valid bool
+	}{
+		{"0 SMTP {owner}", escalationLevel{0, "SMTP", "{owner}"}, true},
+		{"30m test #ops alerts", escalationLevel{30 * time.Minute, "test", "#ops alerts"}, true},
+		{"2h PagerDuty", escalationLevel{2 * time.Hour, "PagerDuty", ""}, true},
+		{"2h", escalationLevel{}, false},
+		{"soon SMTP", escalationLevel{}, false},
+	}
+
+	for _, tt := range tests {
+		level, err := parseEscalationLevel(tt.line)
+		if (err == nil) != tt.valid {
+			t.Fatalf("%s: expected valid %v got [%v]", tt.line, tt.valid, err)
+		}
+		if level != tt.level {
+			t.Fatalf("%s: expected %+v got %+v", tt.line, tt.level, level)
+		}
+	}
+}
+
+func Test_escalate(t *testing.T) {
+	test := setupMonitoring(t, true)
+	other := &otherAlerter{}
+	alertFuncs = append(alertFuncs, other)
+	oldPolicies := escalationPolicies
+	defer func() { escalationPolicies = oldPolicies }()
+
+	conf := Config{}
+	conf.Escalation = map[string]*struct{ Level []string }{
+		"wakeup": {Level: []string{"2h other pager", "0 test {owner}", "30m other #ops"}},
+	}
+	policies, err := parseEscalations(conf)
+	if err != nil {
+		t.Fatalf("Unable to parse escalations [%v]", err)
+	}
+	escalationPolicies = policies
+
+	store := newMemStore(Config{})
+	store.StoreReservation(&reservation{App: "jimtest", Component: "monitor", Owner: "jim@example.com",
+		Frequency: 5, TimeUnits: "minutes", Escalation: "wakeup"})
+	store.StoreCheckin(checkin{App: "gotel", Component: "coordinator"}, time.Now().UTC().Unix())
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1403253684)
+
+	// only the first level is due
+	jobChecker(store)
+	jobChecker(store)
+	if len(test.alerts) != 1 || len(other.alerts) != 0 {
+		t.Fatalf("Expected only the first level to fire got %d/%d", len(test.alerts), len(other.alerts))
+	}
+	if target := test.alerts[0].alerterTarget("test"); target != "jim@example.com" {
+		t.Fatalf("Expected the level target to be formatted got [%s]", target)
+	}
+
+	// pretend the reservation has been failing for 45 minutes
+	res, _ := store.GetReservation("jimtest", "monitor")
+	res.AlertType = alertMissedCheckin
+	store.StoreEscalation(res, escalation{Started: time.Now().UTC().Add(-45 * time.Minute).Unix(), Level: 0})
+	jobChecker(store)
+	if len(test.alerts) != 1 || len(other.alerts) != 1 || other.alerts[0].alerterTarget("other") != "#ops" {
+		t.Fatalf("Expected the 30m level to fire got %d/%d", len(test.alerts), len(other.alerts))
+	}
+
+	// cleaning up old logs keeps the escalation of a reservation that is still failing
+	if err = store.CleanUp(time.Now().UTC().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("Unable to clean up [%v]", err)
+	}
+	if e, _ := store.GetEscalation(res); e.Level != 1 {
+		t.Fatalf("Expected the escalation to survive clean up [%+v]", e)
+	}
+
+	// recovering resolves the levels that fired and ends the escalation
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, time.Now().UTC().Unix())
+	jobChecker(store)
+	jobChecker(store)
+	if len(test.resolved) != 1 || len(other.resolved) != 1 || other.resolved[0].alerterTarget("other") != "#ops" {
+		t.Fatalf("Expected both alerters to resolve got %d/%d", len(test.resolved), len(other.resolved))
+	}
+	if e, _ := store.GetEscalation(res); e.Started != 0 {
+		t.Fatalf("Escalation should be cleared after recovering [%+v]", e)
+	}
+}
+
+func Test_resolveShortenedEscalation(t *testing.T) {
+	test := setupMonitoring(t, true)
+	oldPolicies := escalationPolicies
+	defer func() { escalationPolicies = oldPolicies }()
+	// the policy had three levels when the reservation escalated
+	escalationPolicies = map[string][]escalationLevel{"wakeup": {{Alerter: "test"}}}
+
+	store := newMemStore(Config{})
+	res := reservation{App: "jimtest", Component: "monitor", Escalation: "wakeup", AlertType: alertMissedCheckin}
+	store.StoreEscalation(res, escalation{Started: time.Now().UTC().Unix(), Level: 2})
+	store.StoreAlert(res, []string{"test"})
+
+	resolveAlerts(store, res, alertMissedCheckin)
+	if len(test.resolved) != 1 {
+		t.Fatalf("Expected the alert to resolve got %d", len(test.resolved))
+	}
+}
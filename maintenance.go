@@ -0,0 +1,74 @@
+package gotel
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maintenance is a recurring window during which alerts are held back. It covers one reservation when App and
+// Component are set, every component of an app when Component is empty or "*", or every reservation with Tag.
+type maintenance struct {
+	ID        int      `json:"id"`
+	App       string   `json:"app,omitempty"`
+	Component string   `json:"component,omitempty"`
+	Tag       string   `json:"tag,omitempty"`
+	Schedule  string   `json:"schedule"` // cron expression for when each window starts
+	Timezone  string   `json:"timezone"` // timezone the schedule runs in, defaults to UTC
+	Duration  duration `json:"duration"` // how long each window lasts
+	Reason    string   `json:"reason"`
+	CreatedBy string   `json:"created_by"`
+}
+
+func validateMaintenance(m *maintenance) error {
+	if (m.App == "") == (m.Tag == "") {
+		return errors.New("Invalid scope passed in, set either app (and optionally component) or tag")
+	}
+	if m.Component != "" && m.App == "" {
+		return errors.New("Invalid scope passed in, component needs an app")
+	}
+	if m.Duration <= 0 {
+		return errors.New("Invalid duration passed in")
+	}
+	if _, _, err := parseSchedule(m.Schedule, m.Timezone); err != nil {
+		return fmt.Errorf("Invalid schedule or timezone passed in [%v]", err)
+	}
+	return nil
+}
+
+// covers says whether the window's scope includes the reservation
+func (m maintenance) covers(res reservation) bool {
+	if m.Tag != "" {
+		for _, tag := range res.Tags {
+			if tag == m.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	if m.App != res.App {
+		return false
+	}
+	return m.Component == "" || m.Component == "*" || m.Component == res.Component
+}
+
+// activeAt says whether a window is open at t, which is when the first start after t-Duration isn't after t
+func (m maintenance) activeAt(t time.Time) bool {
+	sched, loc, err := parseSchedule(m.Schedule, m.Timezone)
+	if err != nil {
+		l.warn("Invalid maintenance schedule [%s] for window %d [%v]", m.Schedule, m.ID, err)
+		return false
+	}
+	start := sched.Next(t.Add(-time.Duration(m.Duration)).In(loc))
+	return !start.After(t)
+}
+
+// inMaintenance says whether any of the windows is open for the reservation at t
+func inMaintenance(windows []maintenance, res reservation, t time.Time) bool {
+	for _, m := range windows {
+		if m.covers(res) && m.activeAt(t) {
+			return true
+		}
+	}
+	return false
+}
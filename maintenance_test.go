@@ -0,0 +1,91 @@
+package gotel
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_maintenanceActiveAt(t *testing.T) {
+	// Sundays 01:00-03:00 New York time
+	m := maintenance{App: "db", Schedule: "0 1 * * 0", Timezone: "America/New_York", Duration: duration(2 * time.Hour)}
+	ny, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		active bool
+	}{
+		{"before the window", time.Date(2024, 6, 2, 0, 59, 0, 0, ny), false},
+		{"window opens", time.Date(2024, 6, 2, 1, 0, 0, 0, ny), true},
+		{"inside the window", time.Date(2024, 6, 2, 2, 30, 0, 0, ny), true},
+		{"window closes", time.Date(2024, 6, 2, 3, 0, 0, 0, ny), false},
+		{"monday", time.Date(2024, 6, 3, 2, 0, 0, 0, ny), false},
+	}
+
+	for _, tt := range tests {
+		if active := m.activeAt(tt.at); active != tt.active {
+			t.Fatalf("%s: expected active %v got %v", tt.name, tt.active, active)
+		}
+	}
+}
+
+func Test_maintenanceCovers(t *testing.T) {
+	res := reservation{App: "db", Component: "backup", Tags: []string{"mysql", "prod"}}
+	tests := []struct {
+		name   string
+		window maintenance
+		covers bool
+	}{
+		{"reservation", maintenance{App: "db", Component: "backup"}, true},
+		{"other component", maintenance{App: "db", Component: "vacuum"}, false},
+		{"whole app", maintenance{App: "db"}, true},
+		{"every component", maintenance{App: "db", Component: "*"}, true},
+		{"other app", maintenance{App: "web"}, false},
+		{"tag", maintenance{Tag: "mysql"}, true},
+		{"other tag", maintenance{Tag: "postgres"}, false},
+	}
+
+	for _, tt := range tests {
+		if covers := tt.window.covers(res); covers != tt.covers {
+			t.Fatalf("%s: expected covers %v got %v", tt.name, tt.covers, covers)
+		}
+	}
+}
+
+func Test_maintenanceSuppressesAlerts(t *testing.T) {
+	test := setupMonitoring(t, true)
+	_, store := newTestEndpoint(t)
+	store.StoreReservation(&reservation{App: "jimtest", Component: "monitor", Frequency: 5, TimeUnits: "minutes",
+		Tags: []string{"batch"}})
+	store.StoreCheckin(checkin{App: "gotel", Component: "coordinator"}, time.Now().UTC().Unix())
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1403253684)
+
+	// a window that opens every minute and lasts two is always open
+	window := &maintenance{Tag: "batch", Schedule: "* * * * *", Duration: duration(2 * time.Minute)}
+	if err := validateMaintenance(window); err != nil {
+		t.Fatalf("Expected a valid window [%v]", err)
+	}
+	store.StoreMaintenance(window)
+	jobChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("Expected no alerts during maintenance got %d", len(test.alerts))
+	}
+
+	store.RemoveMaintenance(window.ID)
+	jobChecker(store)
+	if len(test.alerts) != 1 {
+		t.Fatalf("Expected an alert once the window is removed got %d", len(test.alerts))
+	}
+
+	// recovering during the next window still resolves the alert and clears its ack
+	store.StoreAck(&ack{App: "jimtest", Component: "monitor", AckedBy: "jim"}, time.Now().UTC().Unix())
+	store.StoreMaintenance(window)
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, time.Now().UTC().Unix())
+	jobChecker(store)
+	if len(test.resolved) != 1 {
+		t.Fatalf("Expected the recovery to resolve during maintenance got %d", len(test.resolved))
+	}
+	if res, _ := store.GetReservation("jimtest", "monitor"); res.AckedBy != "" {
+		t.Fatalf("Expected the ack to be cleared during maintenance got [%s]", res.AckedBy)
+	}
+}
@@ -0,0 +1,690 @@
+package gotel
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// migration is one numbered step of the GoTel schema. up and down run on every dialect unless the dialect
+// has its own statements in upFor/downFor, keyed by dialect name.
+type migration struct {
+	version     int
+	description string
+	up          []string
+	down        []string
+	upFor       map[string][]string
+	downFor     map[string][]string
+}
+
+func (m migration) statements(d dialect, up bool) []string {
+	if up {
+		if stmts, ok := m.upFor[d.name()]; ok {
+			return stmts
+		}
+		return m.up
+	}
+	if stmts, ok := m.downFor[d.name()]; ok {
+		return stmts
+	}
+	return m.down
+}
+
+// migrations is the full history of the schema, oldest first. Append new steps to the end and never change one
+// that has already shipped.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create alerts, reservations, housekeeping and nodes",
+		upFor: map[string][]string{
+			"mysql": {
+				`CREATE TABLE IF NOT EXISTS alerts (
+				  id int(11) unsigned NOT NULL AUTO_INCREMENT,
+				  app varchar(30) DEFAULT NULL,
+				  component varchar(30) DEFAULT NULL,
+				  alert_time int(11) DEFAULT NULL,
+				  alerters text DEFAULT NULL,
+				  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				  PRIMARY KEY (id)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+				`CREATE TABLE IF NOT EXISTS reservations (
+				  id int(11) NOT NULL AUTO_INCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  owner text DEFAULT NULL,
+				  notify text DEFAULT NULL,
+				  frequency int(11) DEFAULT NULL,
+				  time_units varchar(30) DEFAULT NULL,
+				  inserted_timestamp int(11) DEFAULT NULL,
+				  num_checkins int(11) DEFAULT '0',
+				  last_alert_timestamp int(11) DEFAULT NULL,
+				  last_checkin_timestamp int(11) DEFAULT NULL,
+				  PRIMARY KEY (id),
+				  UNIQUE KEY uniq_app (app,component)
+				) ENGINE=InnoDB AUTO_INCREMENT=2 DEFAULT CHARSET=utf8;`,
+				`CREATE TABLE IF NOT EXISTS housekeeping (
+				  id int(11) NOT NULL AUTO_INCREMENT,
+				  app varchar(30) DEFAULT NULL,
+				  component varchar(30) DEFAULT NULL,
+				  notes text,
+				  last_checkin_timestamp int(11) DEFAULT NULL,
+				  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				  PRIMARY KEY (id)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+				`CREATE TABLE IF NOT EXISTS nodes (
+				  id int(11) unsigned NOT NULL AUTO_INCREMENT,
+				  ip_address varchar(20) DEFAULT NULL,
+				  node_id int(30) DEFAULT NULL,
+				  PRIMARY KEY (id),
+				  UNIQUE KEY uniq_ip (ip_address)
+				) ENGINE=InnoDB AUTO_INCREMENT=7 DEFAULT CHARSET=utf8;`,
+			},
+			"sqlite3": {
+				`CREATE TABLE IF NOT EXISTS alerts (
+				  id INTEGER PRIMARY KEY AUTOINCREMENT,
+				  app varchar(30) DEFAULT NULL,
+				  component varchar(30) DEFAULT NULL,
+				  alert_time integer DEFAULT NULL,
+				  alerters text DEFAULT NULL,
+				  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+				`CREATE TABLE IF NOT EXISTS reservations (
+				  id INTEGER PRIMARY KEY AUTOINCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  owner text DEFAULT NULL,
+				  notify text DEFAULT NULL,
+				  frequency integer DEFAULT NULL,
+				  time_units varchar(30) DEFAULT NULL,
+				  inserted_timestamp integer DEFAULT NULL,
+				  num_checkins integer DEFAULT 0,
+				  last_alert_timestamp integer DEFAULT NULL,
+				  last_checkin_timestamp integer DEFAULT NULL,
+				  UNIQUE (app, component)
+				);`,
+				`CREATE TABLE IF NOT EXISTS housekeeping (
+				  id INTEGER PRIMARY KEY AUTOINCREMENT,
+				  app varchar(30) DEFAULT NULL,
+				  component varchar(30) DEFAULT NULL,
+				  notes text,
+				  last_checkin_timestamp integer DEFAULT NULL,
+				  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+				`CREATE TABLE IF NOT EXISTS nodes (
+				  id INTEGER PRIMARY KEY AUTOINCREMENT,
+				  ip_address varchar(20) DEFAULT NULL UNIQUE,
+				  node_id integer DEFAULT NULL
+				);`,
+			},
+			"postgres": {
+				`CREATE TABLE IF NOT EXISTS alerts (
+				  id SERIAL PRIMARY KEY,
+				  app varchar(30) DEFAULT NULL,
+				  component varchar(30) DEFAULT NULL,
+				  alert_time bigint DEFAULT NULL,
+				  alerters text DEFAULT NULL,
+				  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+				`CREATE TABLE IF NOT EXISTS reservations (
+				  id SERIAL PRIMARY KEY,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  owner text DEFAULT NULL,
+				  notify text DEFAULT NULL,
+				  frequency integer DEFAULT NULL,
+				  time_units varchar(30) DEFAULT NULL,
+				  inserted_timestamp bigint DEFAULT NULL,
+				  num_checkins integer DEFAULT 0,
+				  last_alert_timestamp bigint DEFAULT NULL,
+				  last_checkin_timestamp bigint DEFAULT NULL,
+				  UNIQUE (app, component)
+				);`,
+				`CREATE TABLE IF NOT EXISTS housekeeping (
+				  id SERIAL PRIMARY KEY,
+				  app varchar(30) DEFAULT NULL,
+				  component varchar(30) DEFAULT NULL,
+				  notes text,
+				  last_checkin_timestamp bigint DEFAULT NULL,
+				  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+				`CREATE TABLE IF NOT EXISTS nodes (
+				  id SERIAL PRIMARY KEY,
+				  ip_address varchar(20) DEFAULT NULL UNIQUE,
+				  node_id integer DEFAULT NULL
+				);`,
+			},
+		},
+		down: []string{
+			`DROP TABLE nodes`,
+			`DROP TABLE housekeeping`,
+			`DROP TABLE reservations`,
+			`DROP TABLE alerts`,
+		},
+	},
+	{
+		version:     2,
+		description: "add alert_msg to reservations",
+		up:          []string{`ALTER TABLE reservations ADD COLUMN alert_msg text DEFAULT NULL`},
+		down:        []string{`ALTER TABLE reservations DROP COLUMN alert_msg`},
+	},
+	{
+		version:     3,
+		description: "add cron schedule and timezone to reservations",
+		up: []string{
+			`ALTER TABLE reservations ADD COLUMN schedule varchar(100) DEFAULT NULL`,
+			`ALTER TABLE reservations ADD COLUMN timezone varchar(64) DEFAULT NULL`,
+		},
+		down: []string{
+			`ALTER TABLE reservations DROP COLUMN timezone`,
+			`ALTER TABLE reservations DROP COLUMN schedule`,
+		},
+	},
+	{
+		version:     4,
+		description: "add grace period to reservations",
+		up:          []string{`ALTER TABLE reservations ADD COLUMN grace_seconds integer DEFAULT 0`},
+		down:        []string{`ALTER TABLE reservations DROP COLUMN grace_seconds`},
+	},
+	{
+		version:     5,
+		description: "track run starts and run times",
+		up: []string{
+			`ALTER TABLE reservations ADD COLUMN max_runtime_seconds integer DEFAULT 0`,
+			`ALTER TABLE reservations ADD COLUMN run_started_timestamp bigint DEFAULT NULL`,
+			`ALTER TABLE housekeeping ADD COLUMN started_timestamp bigint DEFAULT NULL`,
+			`ALTER TABLE housekeeping ADD COLUMN runtime_seconds integer DEFAULT NULL`,
+		},
+		down: []string{
+			`ALTER TABLE housekeeping DROP COLUMN runtime_seconds`,
+			`ALTER TABLE housekeeping DROP COLUMN started_timestamp`,
+			`ALTER TABLE reservations DROP COLUMN run_started_timestamp`,
+			`ALTER TABLE reservations DROP COLUMN max_runtime_seconds`,
+		},
+	},
+	{
+		version:     6,
+		description: "record checkin status, exit code and output",
+		up: []string{
+			`ALTER TABLE reservations ADD COLUMN last_status varchar(10) DEFAULT NULL`,
+			`ALTER TABLE reservations ADD COLUMN last_exit_code integer DEFAULT NULL`,
+			`ALTER TABLE reservations ADD COLUMN last_output text`,
+			`ALTER TABLE housekeeping ADD COLUMN status varchar(10) DEFAULT NULL`,
+			`ALTER TABLE housekeeping ADD COLUMN exit_code integer DEFAULT NULL`,
+			`ALTER TABLE housekeeping ADD COLUMN output text`,
+		},
+		down: []string{
+			`ALTER TABLE housekeeping DROP COLUMN output`,
+			`ALTER TABLE housekeeping DROP COLUMN exit_code`,
+			`ALTER TABLE housekeeping DROP COLUMN status`,
+			`ALTER TABLE reservations DROP COLUMN last_output`,
+			`ALTER TABLE reservations DROP COLUMN last_exit_code`,
+			`ALTER TABLE reservations DROP COLUMN last_status`,
+		},
+	},
+	{
+		version:     7,
+		description: "track alert types and when alerts were resolved",
+		up: []string{
+			`ALTER TABLE alerts ADD COLUMN alert_type varchar(30) DEFAULT NULL`,
+			`ALTER TABLE alerts ADD COLUMN resolved_time bigint DEFAULT NULL`,
+		},
+		down: []string{
+			`ALTER TABLE alerts DROP COLUMN resolved_time`,
+			`ALTER TABLE alerts DROP COLUMN alert_type`,
+		},
+	},
+	{
+		version:     8,
+		description: "add per reservation alerter routes",
+		up: []string{
+			`ALTER TABLE reservations ADD COLUMN alerters text`,
+		},
+		down: []string{
+			`ALTER TABLE reservations DROP COLUMN alerters`,
+		},
+	},
+	{
+		version:     9,
+		description: "add escalation policies and escalation state",
+		upFor: map[string][]string{
+			"mysql": {
+				`ALTER TABLE reservations ADD COLUMN escalation varchar(100) DEFAULT NULL`,
+				`CREATE TABLE IF NOT EXISTS escalations (
+				  id int(11) unsigned NOT NULL AUTO_INCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  alert_type varchar(30) DEFAULT NULL,
+				  started_timestamp int(11) DEFAULT NULL,
+				  level int(11) DEFAULT NULL,
+				  PRIMARY KEY (id),
+				  UNIQUE KEY uniq_escalation (app,component,alert_type)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+			},
+			"sqlite3": {
+				`ALTER TABLE reservations ADD COLUMN escalation varchar(100) DEFAULT NULL`,
+				`CREATE TABLE IF NOT EXISTS escalations (
+				  id INTEGER PRIMARY KEY AUTOINCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  alert_type varchar(30) DEFAULT NULL,
+				  started_timestamp integer DEFAULT NULL,
+				  level integer DEFAULT NULL,
+				  UNIQUE (app, component, alert_type)
+				);`,
+			},
+			"postgres": {
+				`ALTER TABLE reservations ADD COLUMN escalation varchar(100) DEFAULT NULL`,
+				`CREATE TABLE IF NOT EXISTS escalations (
+				  id SERIAL PRIMARY KEY,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  alert_type varchar(30) DEFAULT NULL,
+				  started_timestamp bigint DEFAULT NULL,
+				  level integer DEFAULT NULL,
+				  UNIQUE (app, component, alert_type)
+				);`,
+			},
+		},
+		down: []string{
+			`DROP TABLE escalations`,
+			`ALTER TABLE reservations DROP COLUMN escalation`,
+		},
+	},
+	{
+		version:     10,
+		description: "add alert acknowledgement to reservations",
+		up: []string{
+			`ALTER TABLE reservations ADD COLUMN acked_by varchar(150) DEFAULT NULL`,
+			`ALTER TABLE reservations ADD COLUMN acked_timestamp bigint DEFAULT NULL`,
+		},
+		down: []string{
+			`ALTER TABLE reservations DROP COLUMN acked_timestamp`,
+			`ALTER TABLE reservations DROP COLUMN acked_by`,
+		},
+	},
+	{
+		version:     11,
+		description: "keep snoozes as windows instead of moving last_checkin_timestamp",
+		upFor: map[string][]string{
+			"mysql": {
+				`CREATE TABLE IF NOT EXISTS snoozes (
+				  id int(11) unsigned NOT NULL AUTO_INCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  start_timestamp int(11) DEFAULT NULL,
+				  end_timestamp int(11) DEFAULT NULL,
+				  reason text,
+				  created_by varchar(150) DEFAULT NULL,
+				  cancelled_timestamp int(11) DEFAULT NULL,
+				  PRIMARY KEY (id)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+			},
+			"sqlite3": {
+				`CREATE TABLE IF NOT EXISTS snoozes (
+				  id INTEGER PRIMARY KEY AUTOINCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  start_timestamp integer DEFAULT NULL,
+				  end_timestamp integer DEFAULT NULL,
+				  reason text,
+				  created_by varchar(150) DEFAULT NULL,
+				  cancelled_timestamp integer DEFAULT NULL
+				);`,
+			},
+			"postgres": {
+				`CREATE TABLE IF NOT EXISTS snoozes (
+				  id SERIAL PRIMARY KEY,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  start_timestamp bigint DEFAULT NULL,
+				  end_timestamp bigint DEFAULT NULL,
+				  reason text,
+				  created_by varchar(150) DEFAULT NULL,
+				  cancelled_timestamp bigint DEFAULT NULL
+				);`,
+			},
+		},
+		down: []string{
+			`DROP TABLE snoozes`,
+		},
+	},
+	{
+		version:     12,
+		description: "add reservation tags and recurring maintenance windows",
+		upFor: map[string][]string{
+			"mysql": {
+				`ALTER TABLE reservations ADD COLUMN tags text`,
+				`CREATE TABLE IF NOT EXISTS maintenance_windows (
+				  id int(11) unsigned NOT NULL AUTO_INCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  tag varchar(150) DEFAULT NULL,
+				  schedule varchar(100) DEFAULT NULL,
+				  timezone varchar(64) DEFAULT NULL,
+				  duration_seconds int(11) DEFAULT NULL,
+				  reason text,
+				  created_by varchar(150) DEFAULT NULL,
+				  PRIMARY KEY (id)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+			},
+			"sqlite3": {
+				`ALTER TABLE reservations ADD COLUMN tags text`,
+				`CREATE TABLE IF NOT EXISTS maintenance_windows (
+				  id INTEGER PRIMARY KEY AUTOINCREMENT,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  tag varchar(150) DEFAULT NULL,
+				  schedule varchar(100) DEFAULT NULL,
+				  timezone varchar(64) DEFAULT NULL,
+				  duration_seconds integer DEFAULT NULL,
+				  reason text,
+				  created_by varchar(150) DEFAULT NULL
+				);`,
+			},
+			"postgres": {
+				`ALTER TABLE reservations ADD COLUMN tags text`,
+				`CREATE TABLE IF NOT EXISTS maintenance_windows (
+				  id SERIAL PRIMARY KEY,
+				  app varchar(150) DEFAULT NULL,
+				  component varchar(150) DEFAULT NULL,
+				  tag varchar(150) DEFAULT NULL,
+				  schedule varchar(100) DEFAULT NULL,
+				  timezone varchar(64) DEFAULT NULL,
+				  duration_seconds integer DEFAULT NULL,
+				  reason text,
+				  created_by varchar(150) DEFAULT NULL
+				);`,
+			},
+		},
+		down: []string{
+			`DROP TABLE maintenance_windows`,
+			`ALTER TABLE reservations DROP COLUMN tags`,
+		},
+	},
+	{
+		version:     13,
+		description: "add per reservation alert severity",
+		up: []string{
+			`ALTER TABLE reservations ADD COLUMN severity varchar(20) DEFAULT NULL`,
+		},
+		down: []string{
+			`ALTER TABLE reservations DROP COLUMN severity`,
+		},
+	},
+	{
+		version:     14,
+		description: "add per reservation email subjects",
+		up: []string{
+			`ALTER TABLE reservations ADD COLUMN email_subject varchar(255) DEFAULT NULL`,
+		},
+		down: []string{
+			`ALTER TABLE reservations DROP COLUMN email_subject`,
+		},
+	},
+}
+
+// latestMigration is the schema version this binary was built for
+func latestMigration() int {
+	return migrations[len(migrations)-1].version
+}
+
+// schemaMigrationsTable records every migration that has been applied, it replaces the per table
+// tables_versions bookkeeping which is left in place untouched for older binaries
+const schemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version integer NOT NULL PRIMARY KEY,
+		  description varchar(255) DEFAULT NULL,
+		  applied_timestamp bigint DEFAULT NULL
+		)`
+
+// prepareMigrations creates the schema_migrations table. Databases bootstrapped before migrations existed are
+// adopted by translating their tables_versions entries into the migrations they are equivalent to.
+func (s *sqlStore) prepareMigrations() error {
+	tables, err := s.tables()
+	if err != nil {
+		return err
+	}
+	if tables["schema_migrations"] {
+		return nil
+	}
+	if _, err = s.db.Exec(schemaMigrationsTable); err != nil {
+		return fmt.Errorf("Unable to create schema_migrations [%v]", err)
+	}
+	if !tables["tables_versions"] {
+		return nil
+	}
+
+	var ver int
+	err = s.db.QueryRow(s.dialect.rebind("SELECT table_version FROM tables_versions WHERE table_name=?"), "reservations").Scan(&ver)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Unable to read tables_versions [%v]", err)
+	}
+	// reservations version 0 is the initial layout, version 1 added alert_msg
+	adopted := 1
+	if ver >= 1 {
+		adopted = 2
+	}
+	l.info("Adopting tables_versions schema as migration version %d", adopted)
+	for _, m := range migrations[:adopted] {
+		if err = s.recordMigration(s.db, m, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tables returns the set of tables in the GoTel database
+func (s *sqlStore) tables() (map[string]bool, error) {
+	rows, err := s.db.Query(s.dialect.tablesQuery())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to select tables [%v]", err)
+	}
+	defer rows.Close()
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var tbl string
+		if err = rows.Scan(&tbl); err != nil {
+			return nil, fmt.Errorf("Unable to scan table rows [%v]", err)
+		}
+		tables[tbl] = true
+	}
+	return tables, rows.Err()
+}
+
+// appliedMigrations returns the applied versions and when they were applied
+func (s *sqlStore) appliedMigrations() (map[int]int64, error) {
+	rows, err := s.db.Query("SELECT version, applied_timestamp FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to select schema_migrations [%v]", err)
+	}
+	defer rows.Close()
+	applied := make(map[int]int64)
+	for rows.Next() {
+		var (
+			ver int
+			ts  sql.NullInt64
+		)
+		if err = rows.Scan(&ver, &ts); err != nil {
+			return nil, fmt.Errorf("Unable to scan schema_migrations [%v]", err)
+		}
+		applied[ver] = ts.Int64
+	}
+	return applied, rows.Err()
+}
+
+// schemaVersion returns the highest applied migration
+func (s *sqlStore) schemaVersion() (int, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	ver := 0
+	for v := range applied {
+		if v > ver {
+			ver = v
+		}
+	}
+	return ver, nil
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *sqlStore) recordMigration(e execer, m migration, up bool) error {
+	var err error
+	if up {
+		_, err = e.Exec(s.dialect.rebind("INSERT INTO schema_migrations (version, description, applied_timestamp) VALUES (?, ?, ?)"),
+			m.version, m.description, time.Now().UTC().Unix())
+	} else {
+		_, err = e.Exec(s.dialect.rebind("DELETE FROM schema_migrations WHERE version=?"), m.version)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to record migration %d [%v]", m.version, err)
+	}
+	return nil
+}
+
+// runMigration applies or reverts a single migration in a transaction. MySQL commits DDL implicitly so a
+// failure part way through a migration there needs fixing by hand.
+func (s *sqlStore) runMigration(m migration, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+	l.info("Running migration %d %s [%s]", m.version, direction, m.description)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not start transaction [%v]", err)
+	}
+	for _, stmt := range m.statements(s.dialect, up) {
+		if _, err = tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Migration %d %s failed [%v]", m.version, direction, err)
+		}
+	}
+	if err = s.recordMigration(tx, m, up); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateUp applies up to steps pending migrations, all of them when steps is 0
+func (s *sqlStore) migrateUp(steps int) error {
+	ver, err := s.schemaVersion()
+	if err != nil {
+		return err
+	}
+	if ver > latestMigration() {
+		return fmt.Errorf("DB schema is at version %d but this GoTel only knows up to version %d, refusing to touch it",
+			ver, latestMigration())
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	ran := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		if steps > 0 && ran == steps {
+			break
+		}
+		if err = s.runMigration(m, true); err != nil {
+			return err
+		}
+		ran++
+	}
+	return nil
+}
+
+// migrateDown reverts the latest steps applied migrations
+func (s *sqlStore) migrateDown(steps int) error {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+		if err = s.runMigration(m, false); err != nil {
+			return err
+		}
+		steps--
+	}
+	return nil
+}
+
+func (s *sqlStore) migrationStatus(w io.Writer) error {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED\tDESCRIPTION")
+	for _, m := range migrations {
+		state, when := "pending", ""
+		if ts, ok := applied[m.version]; ok {
+			state, when = "applied", time.Unix(ts, 0).UTC().Format(time.RFC1123)
+			delete(applied, m.version)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", m.version, state, when, m.description)
+	}
+	// anything left over was applied by a newer GoTel
+	for ver, ts := range applied {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", ver, "unknown", time.Unix(ts, 0).UTC().Format(time.RFC1123), "applied by a newer GoTel")
+	}
+	return tw.Flush()
+}
+
+// Migrate runs the migrate subcommand against the store, one of status, up [n] or down [n].
+// up applies every pending migration by default, down reverts the latest one.
+func Migrate(store Store, args []string, w io.Writer) error {
+	s, ok := store.(*sqlStore)
+	if !ok {
+		return errors.New("this store has no schema to migrate")
+	}
+	if len(args) == 0 {
+		return errors.New("usage: migrate status|up [n]|down [n]")
+	}
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of migrations [%s]", args[1])
+		}
+		steps = n
+	}
+	if err := s.prepareMigrations(); err != nil {
+		return err
+	}
+
+	var err error
+	switch args[0] {
+	case "status":
+		return s.migrationStatus(w)
+	case "up":
+		err = s.migrateUp(steps)
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		err = s.migrateDown(steps)
+	default:
+		return fmt.Errorf("unknown migrate command [%s]", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	return s.migrationStatus(w)
+}
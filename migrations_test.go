@@ -0,0 +1,24 @@
+package gotel
+
+import (
+	"testing"
+)
+
+func Test_migrationsOrdered(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("Migration %d has version %d, versions must count up from 1 without gaps", i, m.version)
+		}
+		if m.description == "" {
+			t.Fatalf("Migration %d needs a description", m.version)
+		}
+		for _, d := range []dialect{&mysqlDialect{}, &sqliteDialect{}, &postgresDialect{}} {
+			if len(m.statements(d, true)) == 0 {
+				t.Fatalf("Migration %d has no up statements for %s", m.version, d.name())
+			}
+			if len(m.statements(d, false)) == 0 {
+				t.Fatalf("Migration %d has no down statements for %s", m.version, d.name())
+			}
+		}
+	}
+}
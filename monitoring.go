@@ -1,8 +1,6 @@
 package gotel
 
 import (
-	"database/sql"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -11,20 +9,47 @@ import (
 	"time"
 )
 
+const (
+	// alertMissedCheckin is sent when a reservation fails its checkin SLA
+	alertMissedCheckin = "missed_checkin"
+	// alertMaxRuntime is sent when a started run goes over its max runtime
+	alertMaxRuntime = "max_runtime"
+	// alertJobFailed is sent on the next job check after a job checks in with a fail status
+	alertJobFailed = "job_failed"
+
+	defaultAlertMessage = "App: [{app}] Component: [{component}] failed checkin on IP [{srv}]. Contact owner [{owner}]"
+	maxRuntimeMessage   = "App: [{app}] Component: [{component}] started {started} and is still running, over its max runtime of {max_runtime}. Contact owner [{owner}]"
+	resolvedMessage     = "App: [{app}] Component: [{component}] has recovered, last checkin {last}. Contact owner [{owner}]"
+	jobFailedMessage    = "App: [{app}] Component: [{component}] reported a failed run with exit code {exitcode}. Contact owner [{owner}]\n{output}"
+)
+
+// alertTitle is a short headline for the reservation's alert type, for subjects and chat titles
+func alertTitle(res reservation) string {
+	switch res.AlertType {
+	case alertMaxRuntime:
+		return "Job Over Max Runtime"
+	case alertJobFailed:
+		return "Job Run Failed"
+	case alertDegraded:
+		return "Job Runs Degraded"
+	}
+	return "Job Failed to checkin"
+}
+
 type alerter interface {
 	Alert(res reservation) bool
+	// Resolve tells the alerter that a reservation it alerted on has recovered
+	Resolve(res reservation) bool
 	Name() string
 	Bootstrap()
 }
 
 var (
-	// holds info so we don't spam alerters every n seconds
-	sentAlerts = make(map[string]time.Time)
 	// this designates the instance as the coordinating instanceS
 	coordinator = false
 	// stores a slice of alerter functions to call when we have an alert
 	alertFuncs = []alerter{}
-	cfg Config
+	cfg        Config
 	// our current IP address
 	myIP string
 )
@@ -39,36 +64,90 @@ func init() {
 }
 
 // Monitor checks existing reservations for late arrivals
-func Monitor(db *sql.DB) {
+func Monitor(store Store) {
 	if !coordinator {
-		coordinator = isCoordinator(db)
+		coordinator = isCoordinator(store)
 	}
 	printCoordinatorStatus()
-	jobChecker(db)
+	jobChecker(store)
 }
 
 // InitializeMonitoring sets up alerters based on configuration
-func InitializeMonitoring(c Config, db *sql.DB) {
+func InitializeMonitoring(c Config, store Store) {
 	cfg = c
 	if cfg.SMTP.Enabled {
 		smtp := new(smtpAlerter)
 		smtp.Cfg = c
+		smtp.Store = store
 		alertFuncs = append(alertFuncs, smtp)
 	} else {
 		l.info("SMTP Alerting disabled")
 	}
-	if cfg.PagerDuty.Enabled {
+	if cfg.PagerDuty.Enabled && cfg.PagerDuty.Version == 2 {
+		pd := new(pagerDutyV2Alerter)
+		pd.Cfg = c
+		alertFuncs = append(alertFuncs, pd)
+	} else if cfg.PagerDuty.Enabled {
 		pd := new(pagerDutyAlerter)
 		pd.Cfg = c
 		alertFuncs = append(alertFuncs, pd)
 	} else {
 		l.info("PagerDuty Alerting disabled")
 	}
+	if cfg.Opsgenie.Enabled {
+		opsgenie := new(opsgenieAlerter)
+		opsgenie.Cfg = c
+		alertFuncs = append(alertFuncs, opsgenie)
+	} else {
+		l.info("Opsgenie Alerting disabled")
+	}
+	if cfg.Slack.Enabled {
+		slack := new(slackAlerter)
+		slack.Cfg = c
+		alertFuncs = append(alertFuncs, slack)
+	} else {
+		l.info("Slack Alerting disabled")
+	}
+	if cfg.Teams.Enabled {
+		teams := new(teamsAlerter)
+		teams.Cfg = c
+		alertFuncs = append(alertFuncs, teams)
+	} else {
+		l.info("Teams Alerting disabled")
+	}
+	if cfg.Mattermost.Enabled {
+		mattermost := new(mattermostAlerter)
+		mattermost.Cfg = c
+		alertFuncs = append(alertFuncs, mattermost)
+	} else {
+		l.info("Mattermost Alerting disabled")
+	}
+	if cfg.Webhook.Enabled {
+		webhook := new(webhookAlerter)
+		webhook.Cfg = c
+		alertFuncs = append(alertFuncs, webhook)
+	} else {
+		l.info("Webhook Alerting disabled")
+	}
 
 	for _, alerter := range alertFuncs {
 		alerter.Bootstrap()
 	}
 
+	policies, err := parseEscalations(c)
+	if err != nil {
+		l.err("Escalation config error [%v]", err)
+		panic("Unable to initialize escalation policies")
+	}
+	escalationPolicies = policies
+
+	windows, err := parseDigests(c)
+	if err != nil {
+		l.err("Digest config error [%v]", err)
+		panic("Unable to initialize digests")
+	}
+	digestWindows = windows
+
 	// set up a ticker that runs every day that checks to clean up old logs to preserve disk space
 
 	ticker := time.NewTicker(24 * time.Hour)
@@ -76,78 +155,52 @@ func InitializeMonitoring(c Config, db *sql.DB) {
 		for t := range ticker.C {
 			if coordinator {
 				l.info("Running log cleanup at [%v]", t)
-				cleanUp(db, c.Main.DaysToStoreLogs)
+				cleanUp(store, c.Main.DaysToStoreLogs)
 			}
 		}
 	}()
 
-}
-
-//--------------------- PRIVATE FUNCS ------------------------------
-
-func hasLock(db *sql.DB) bool {
-	var lck int
-	query := "SELECT GET_LOCK('gotel_lock', 3) as lck"
-	rows, err := db.Query(query)
-	if err != nil {
-		l.warn("Unable to acquire lock\n")
+	// run times only change when jobs check in so there's no need to look for regressions on every job check
+	if cfg.Regression.Enabled {
+		regressionTicker := time.NewTicker(10 * time.Minute)
+		go func() {
+			for t := range regressionTicker.C {
+				if coordinator {
+					l.info("Running regression checker at [%v]", t)
+					regressionChecker(store)
+				}
+			}
+		}()
+	} else {
+		l.info("Run time regression checks disabled")
 	}
-	defer rows.Close()
-	for rows.Next() {
-		err := rows.Scan(&lck)
-		if err != nil {
-			l.warn("Unable to acquire locking rows\n")
-		}
-		if lck == 1 {
-			// holds a lock while the connection is alive
-			l.info("Lock Acquired")
-			return true
-		}
-		l.info("Unable to acquire coordinator lock. I must be a worker [%v]", lck)
 
-	}
-	return false
 }
 
-func releaseLock(db *sql.DB) (bool, error) {
-	releaseQuery := "SELECT RELEASE_LOCK('gotel_lock');"
-	rows, err := db.Query(releaseQuery)
-	if err != nil {
-		l.warn("Unable to release lock\n")
-		return false, errors.New("Unable to release lock")
-	}
-	defer rows.Close()
-	return true, nil
-
-}
+//--------------------- PRIVATE FUNCS ------------------------------
 
 // attempt to acquire coordinator lock to indicate this node should do the job checking
 // in the future I'd like to have a zookeeper integration for a more "true" leader election scheme
 // this is somewhat of a quickstart method so people don't have to also have ZK in their env
 // split brain would be detected by the coordinator not checking in so we'd be firing off an alert
-func isCoordinator(db *sql.DB) bool {
+func isCoordinator(store Store) bool {
 
 	coordinatorNodeCnt := 0
 
-	lockAcquired := hasLock(db)
+	lockAcquired := store.HasLock()
 	if lockAcquired {
-		var (
-			ipAddress string
-			nodeID    int64
-		)
-		rows, err := db.Query("SELECT ip_address, node_id FROM nodes")
+		nodes, err := store.ListNodes()
 		if err != nil {
 			l.err("Unable to select nodes [%v]", err)
+			_, err = store.ReleaseLock()
+			if err != nil {
+				l.err("Unable to release lock [%v]", err)
+			}
 			return false
 		}
-		defer rows.Close()
 
-		for rows.Next() {
-			err := rows.Scan(&ipAddress, &nodeID)
-			if err != nil {
-				l.err("Unable to scan node rows [%v]", err)
-				return false
-			}
+		for _, n := range nodes {
+			ipAddress := n.IPAddress
 			if ipAddress == myIP {
 				continue
 			}
@@ -156,7 +209,7 @@ func isCoordinator(db *sql.DB) bool {
 			resp, err := http.Get(fmt.Sprintf("http://%s:8080/is-coordinator", ipAddress))
 			if err != nil {
 				l.warn("Unable to contact node [%s] assuming offline", ipAddress)
-				removeNode(db, ipAddress)
+				removeNode(store, ipAddress)
 				continue
 			}
 			defer resp.Body.Close()
@@ -176,8 +229,8 @@ func isCoordinator(db *sql.DB) bool {
 			l.info("ip [%s] http coordinator check returned [%s]", ipAddress, body)
 
 		}
-		insertSelf(db)
-		_, err = releaseLock(db)
+		insertSelf(store)
+		_, err = store.ReleaseLock()
 		if err != nil {
 			l.err("Unable to release lock [%v]", err)
 		}
@@ -189,7 +242,7 @@ func isCoordinator(db *sql.DB) bool {
 	return false
 }
 
-func removeNode(db *sql.DB, ipAddress string) {
+func removeNode(store Store, ipAddress string) {
 	external, err := externalIP()
 	if external == ipAddress {
 		return
@@ -197,19 +250,13 @@ func removeNode(db *sql.DB, ipAddress string) {
 	if err != nil {
 		l.warn("Unable to delete offline node [%v]", err)
 	}
-	stmt, err := db.Prepare("DELETE FROM nodes WHERE ip_address=?")
-	if err != nil {
-		l.warn("Unable to prepare record %s", err)
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(ipAddress)
-	if err != nil {
-		l.warn("Unable to run delete operation for remove node [%v]", err)
+	if err = store.RemoveNode(ipAddress); err != nil {
+		return
 	}
 	l.info("Node [%s] was removed from DB", ipAddress)
 }
 
-func insertSelf(db *sql.DB) {
+func insertSelf(store Store) {
 	ip, err := externalIP()
 	if err != nil {
 		l.err("Unable to get external IP [%v]", err)
@@ -217,20 +264,9 @@ func insertSelf(db *sql.DB) {
 	rand.Seed(time.Now().UnixNano())
 	seedID := rand.Intn(10000)
 
-	stmt, err := db.Prepare("INSERT INTO nodes(ip_address, node_id) VALUES (?, ?)")
+	err = store.InsertNode(ip, seedID)
 	if err != nil {
-		l.err("Unable to prepare insertself record %s", err)
-		return
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(ip, seedID)
-	if err != nil {
-		if strings.Contains(err.Error(), "Duplicate entry") {
-			l.info("[%s] has already registered as a node", ip)
-		} else {
-			l.warn("Unable to insert insertself record %s", err)
-		}
-		return
+		l.warn("Unable to register [%s] as a node [%v]", ip, err)
 	}
 }
 
@@ -245,68 +281,251 @@ func printCoordinatorStatus() {
 // checks jobs and sends to workers to check on last update time
 // we're not on the master we want to monitor the master to make sure it's running it's job checker
 // mode will be master if the main jobs should run on this node
-func jobChecker(db *sql.DB) {
+func jobChecker(store Store) {
 
-	var query string
+	var reservations []reservation
 	if coordinator {
-		query = "SELECT id, app, component, owner, notify, alert_msg, frequency, time_units, last_checkin_timestamp FROM reservations"
+		var err error
+		reservations, err = store.ListReservations()
+		if err != nil {
+			l.err("Unable to run job checker [%v]", err)
+			return
+		}
 	} else {
 		// if we're a worker we just want to monitor the co-ordinator
-		query = "SELECT id, app, component, owner, notify, alert_msg, frequency, time_units, last_checkin_timestamp FROM reservations WHERE app='gotel' AND component='coordinator'"
+		res, err := store.GetReservation("gotel", "coordinator")
+		if err != nil {
+			l.err("Unable to run job checker [%v]", err)
+			return
+		}
+		reservations = []reservation{res}
+	}
+	if err := applySnoozes(store, reservations, time.Now().UTC()); err != nil {
+		l.err("Unable to run job checker [%v]", err)
+		return
 	}
-	rows, err := db.Query(query)
-	defer rows.Close()
+	windows, err := store.ListMaintenance()
 	if err != nil {
 		l.err("Unable to run job checker [%v]", err)
 		return
 	}
 
-	for rows.Next() {
-		var alertMessage sql.NullString
-		res := reservation{}
-		err = rows.Scan(&res.JobID, &res.App, &res.Component, &res.Owner, &res.Notify, &alertMessage, &res.Frequency,
-			&res.TimeUnits, &res.LastCheckin)
-		if err != nil {
-			l.err("Unable to scan rows [%v]", err)
-			return
+	for _, res := range reservations {
+		// a maintenance window only holds back new alerts, recoveries are still resolved and acks cleared
+		maintenance := inMaintenance(windows, res, time.Now().UTC())
+		if maintenance {
+			l.info("App is in a maintenance window [%s/%s], not alerting", res.App, res.Component)
 		}
-
 		if FailsSLA(res) {
-			if (!alertMessage.Valid) || (alertMessage.String == "") {
-				alertMessage.String = "App: [{app}] Component: [{component}] failed checkin on IP [{srv}]. Contact owner [{owner}]"
+			alertMessage := res.AlertMessage
+			if alertMessage == "" {
+				alertMessage = defaultAlertMessage
+			}
+			if !maintenance {
+				sendAlerts(store, res, alertMissedCheckin, alertMessage)
+			}
+		} else {
+			resolveAlerts(store, res, alertMissedCheckin)
+		}
+		if overMaxRuntime(res, time.Now().UTC()) {
+			l.info("App over max runtime [%s/%s] started at %d", res.App, res.Component, res.RunStarted)
+			if res.SnoozedUntil > time.Now().UTC().Unix() {
+				l.info("App is snoozed [%s/%s], not alerting on its long run", res.App, res.Component)
+			} else if !maintenance {
+				sendAlerts(store, res, alertMaxRuntime, maxRuntimeMessage)
+			}
+		} else {
+			resolveAlerts(store, res, alertMaxRuntime)
+		}
+		if res.LastStatus == checkinFail {
+			if res.SnoozedUntil > time.Now().UTC().Unix() {
+				l.info("App is snoozed [%s/%s], not alerting on its failed run", res.App, res.Component)
+			} else if !maintenance {
+				sendAlerts(store, res, alertJobFailed, jobFailedMessage)
 			}
-			res.AlertMessage = res.formatAlert(alertMessage.String)
-			for _, alerter := range alertFuncs {
-				if !alreadySentRecently(res, alerter.Name()) {
-					if alerter.Alert(res) {
-						updateSentRecently(res, alerter.Name())
-						storeAlert(res, db, []string{alerter.Name()})
-					}
-				} else {
-					l.info("Already sent alert for [%s/%s/%s]", res.App, res.Component, alerter.Name())
+		} else {
+			resolveAlerts(store, res, alertJobFailed)
+		}
+		if res.AckedBy != "" && !res.failing(time.Now().UTC()) {
+			l.info("App recovered [%s/%s], clearing ack by [%s]", res.App, res.Component, res.AckedBy)
+			if err := store.ClearAck(res.App, res.Component); err != nil {
+				l.err("Unable to clear ack [%v]", err)
+			}
+		}
+	}
+	flushDigests(store, time.Now().UTC())
+	storeJobRun(store)
+}
+
+// sendAlerts formats the alert message and hands the reservation to every alerter that hasn't had this alert recently
+func sendAlerts(store Store, res reservation, alertType, format string) {
+	res.AlertType = alertType
+	if res.AckedBy != "" {
+		l.info("Not sending %s alert for [%s/%s], acked by [%s]", alertType, res.App, res.Component, res.AckedBy)
+		return
+	}
+	res.AlertMessage = res.formatAlert(format)
+	if levels, ok := escalationPolicies[res.Escalation]; ok {
+		escalate(store, res, levels)
+		return
+	}
+	for _, alerter := range alertFuncs {
+		if !res.routesTo(alerter.Name()) {
+			continue
+		}
+		if !alreadySentRecently(store, res, alerter.Name()) {
+			if queueDigest(alerter, res) {
+				continue
+			}
+			if alerter.Alert(res) {
+				err := store.StoreAlert(res, []string{alerter.Name()})
+				if err != nil {
+					l.err("Unable to store alert [%v]", err)
 				}
 			}
+		} else {
+			l.info("Already sent %s alert for [%s/%s/%s]", alertType, res.App, res.Component, alerter.Name())
+		}
+	}
+}
+
+// resolveAlerts tells the alerters that fired an alert type for a reservation that it has recovered. Alerters that
+// fail to resolve are tried again on the next job check.
+func resolveAlerts(store Store, res reservation, alertType string) {
+	res.AlertType = alertType
+	dropDigest(res)
+	names, err := store.FiringAlerters(res)
+	if err != nil {
+		l.err("Unable to list firing alerters for [%s/%s] [%v]", res.App, res.Component, err)
+		return
+	}
+	if levels, ok := escalationPolicies[res.Escalation]; ok {
+		state, err := store.GetEscalation(res)
+		if err != nil {
+			l.err("Unable to load escalation for [%s/%s] [%v]", res.App, res.Component, err)
+			return
+		}
+		if state.Started > 0 {
+			if len(names) == 0 {
+				if err = store.ClearEscalation(res); err != nil {
+					l.err("Unable to clear escalation for [%s/%s] [%v]", res.App, res.Component, err)
+				}
+				return
+			}
+			// the policy may have lost levels since they fired
+			fired := state.Level + 1
+			if fired > len(levels) {
+				fired = len(levels)
+			}
+			res.Alerters = escalationRoutes(res, levels[:fired])
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	firing := make(map[string]bool)
+	for _, name := range names {
+		firing[name] = true
+	}
+	l.info("App recovered [%s/%s] from %s", res.App, res.Component, alertType)
+	res.AlertMessage = res.formatAlert(resolvedMessage)
+	for _, alerter := range alertFuncs {
+		if !firing[alerter.Name()] {
+			continue
+		}
+		if alerter.Resolve(res) {
+			if err = store.ResolveAlert(res, alerter.Name()); err != nil {
+				l.err("Unable to store resolved alert [%v]", err)
+			}
+		} else {
+			l.warn("Unable to resolve %s alert for [%s/%s/%s]", alertType, res.App, res.Component, alerter.Name())
+		}
+	}
+}
+
+// applySnoozes sets SnoozedUntil on the reservations to the end of their latest snooze window that has started
+func applySnoozes(store Store, reservations []reservation, now time.Time) error {
+	snoozes, err := store.ListSnoozes(0)
+	if err != nil {
+		return err
+	}
+	ends := make(map[[2]string]int64)
+	for _, p := range snoozes {
+		key := [2]string{p.App, p.Component}
+		if p.Start <= now.Unix() && p.End > ends[key] {
+			ends[key] = p.End
+		}
+	}
+	for i := range reservations {
+		reservations[i].SnoozedUntil = ends[[2]string{reservations[i].App, reservations[i].Component}]
+	}
+	return nil
+}
+
+// failing says whether anything about the reservation is currently alerting
+func (r *reservation) failing(now time.Time) bool {
+	return slaStatus(*r, now) == slaFailing || overMaxRuntime(*r, now) || r.LastStatus == checkinFail
+}
+
+// overMaxRuntime checks if a run in progress has gone on longer than the reservation allows
+func overMaxRuntime(res reservation, now time.Time) bool {
+	if res.RunStarted == 0 || res.MaxRuntime <= 0 {
+		return false
+	}
+	return now.Sub(time.Unix(res.RunStarted, 0)) > time.Duration(res.MaxRuntime)
+}
+
+// routesTo says whether the reservation alerts through the named alerter, reservations without routes use them all
+func (r *reservation) routesTo(alerterName string) bool {
+	if len(r.Alerters) == 0 {
+		return true
+	}
+	for _, route := range r.Alerters {
+		if strings.EqualFold(route.Name, alerterName) {
+			return true
+		}
+	}
+	return false
+}
+
+// alerterTarget returns where the reservation wants the named alerter to send to, empty for the alerter's default
+func (r *reservation) alerterTarget(alerterName string) string {
+	for _, route := range r.Alerters {
+		if strings.EqualFold(route.Name, alerterName) {
+			return route.Target
 		}
 	}
-	storeJobRun(db)
+	return ""
 }
 
+// mapKey identifies an alert type for a reservation, and alerter when given, in GoTel's own maps. The parts are
+// joined with a NUL so "ab"/"c" and "a"/"bc" don't share a key.
 func (r *reservation) mapKey(alerterName string) string {
-	return r.App + r.Component + alerterName
+	return strings.Join([]string{r.App, r.Component, r.AlertType, alerterName}, "\x00")
 }
 
-// check to see if we've already sent this alert recently
-func alreadySentRecently(res reservation, alerterName string) bool {
+// alertKey identifies an alert type for a reservation to outside alerting systems, so repeats deduplicate there
+func (r *reservation) alertKey() string {
+	return "gotel/" + r.App + "/" + r.Component + "/" + r.AlertType
+}
+
+// check to see if we've already sent this alert recently, the last sent time is kept in the store so it survives
+// restarts and is shared with the other nodes
+func alreadySentRecently(store Store, res reservation, alerterName string) bool {
 	timeNow := time.Now().UTC()
 	waitForNotifyTime := time.Duration(cfg.Main.HoursBetweenAlerts) * time.Hour
 
-	lastSent, ok := sentAlerts[res.mapKey(alerterName)]
-	if !ok {
+	last, err := store.LastAlertTime(res, alerterName)
+	if err != nil {
+		l.warn("Unable to look up last alert for [%s/%s/%s] [%v]", res.App, res.Component, alerterName, err)
+		return false
+	}
+	if last == 0 {
 		return false
 	}
 
 	// check to see if the time elapsed goes over our threshold
-	duration := timeNow.Sub(lastSent)
+	duration := timeNow.Sub(time.Unix(last, 0))
 	if duration >= waitForNotifyTime {
 		return false
 	}
@@ -314,50 +533,57 @@ func alreadySentRecently(res reservation, alerterName string) bool {
 	return true
 }
 
-func updateSentRecently(res reservation, alerterName string) {
-	sentAlerts[res.mapKey(alerterName)] = time.Now()
+const (
+	slaOK      = "ok"
+	slaLate    = "late"
+	slaFailing = "failing"
+	slaSnoozed = "snoozed"
+)
+
+// slaStatus says whether a reservation is snoozed, ok, late (past its checkin but within its grace period) or failing
+func slaStatus(res reservation, now time.Time) string {
+	if res.SnoozedUntil > now.Unix() {
+		return slaSnoozed
+	}
+	due := res.checkinDue()
+	if !now.After(due) {
+		return slaOK
+	}
+	if now.After(due.Add(time.Duration(res.Grace))) {
+		return slaFailing
+	}
+	return slaLate
 }
 
 // FailsSLA monitors the reservations and determines if any jobs haven't checked in within
-// their allotted timeframe
+// their allotted timeframe plus grace period
 func FailsSLA(res reservation) bool {
 	l.info("Working on app [%s] component [%s]", res.App, res.Component)
 
 	timeNow := time.Now().UTC()
 	startTime := time.Unix(res.LastCheckin, 0)
 	secondsAgo := int(timeNow.Sub(startTime).Seconds())
-	if secondsAgo > getSecondsFromUnits(res.Frequency, res.TimeUnits) {
+	switch slaStatus(res, timeNow) {
+	case slaFailing:
 		// send job to alert on
 		l.info("App Failed SLA [%s/%s] that is: %d seconds old\n", res.App, res.Component, secondsAgo)
 		return true
+	case slaSnoozed:
+		l.info("App is snoozed [%s/%s] until %v\n", res.App, res.Component, time.Unix(res.SnoozedUntil, 0).UTC())
+	case slaLate:
+		l.info("App is late [%s/%s] that is: %d seconds old, still within its grace period\n", res.App, res.Component, secondsAgo)
 	}
 	return false
 }
 
-func storeAlert(res reservation, db *sql.DB, alerters []string) {
-	now := time.Now().UTC().Unix()
-	altertNames := strings.Join(alerters, ",")
-	stmt, err := db.Prepare("INSERT INTO alerts(app, component, alert_time, alerters) VALUES (?, ?, ?, ?)")
-	if err != nil {
-		l.info("[ERROR] Unable to prepare storealert record %s", err)
-		return
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(res.App, res.Component, now, altertNames)
-	if err != nil {
-		l.info("[ERROR] Unable to insert alert record %s", err)
-		return
-	}
-}
-
-func storeJobRun(db *sql.DB) {
+func storeJobRun(store Store) {
 	mode := "worker"
 	if coordinator {
 		mode = "coordinator"
 	}
 	now := time.Now().UTC().Unix()
 	l.info("Storing job run, mode: [%s]\n", mode)
-	_, err := storeCheckin(db, checkin{
+	_, err := store.StoreCheckin(checkin{
 		App:       "gotel",
 		Component: mode,
 	}, now)
@@ -368,35 +594,13 @@ func storeJobRun(db *sql.DB) {
 
 // Cleanup should run on a scheduled ticker to allow GoTel to clean up after itself to prevent disk space issues in the
 // DB as the process is meant to run for years.
-func cleanUp(db *sql.DB, daysToStoreLogs int) {
+func cleanUp(store Store, daysToStoreLogs int) {
 
 	// grab the unix time that was daysToStoreLogs ago, cleanup anything older than that to keep db size down
 	timeNow := time.Now().UTC().AddDate(0, 0, -daysToStoreLogs).Unix()
 
-	// clean up housekeeping
-	stmt, err := db.Prepare("DELETE FROM housekeeping WHERE last_checkin_timestamp < ?")
+	err := store.CleanUp(timeNow)
 	if err != nil {
-		l.err("Unable to prepare cleaup housekeeping statement")
-		return
+		l.err("Unable to clean up old logs [%v]", err)
 	}
-	defer stmt.Close()
-	_, err = stmt.Exec(timeNow)
-	if err != nil {
-		l.err("Unable cleanup old housekeeping logs, this could be bad [%v]", err)
-		return
-	}
-
-	// clean up alerts
-	stmt, err = db.Prepare("DELETE FROM alerts WHERE alert_time < ?")
-	if err != nil {
-		l.err("Unable to prepare cleaup alerts statement")
-		return
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(timeNow)
-	if err != nil {
-		l.err("Unable cleanup old alerts logs, this could be bad [%v]", err)
-		return
-	}
-
 }
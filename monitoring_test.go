@@ -1,6 +1,8 @@
 package gotel
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -35,3 +37,334 @@ func Test_goodSLA(t *testing.T) {
 		t.Fatalf("Should not have failed checkin SLA, within 5 minutes")
 	}
 }
+
+type testAlerter struct {
+	alerts   []reservation
+	resolved []reservation
+}
+
+func (a *testAlerter) Alert(res reservation) bool {
+	a.alerts = append(a.alerts, res)
+	return true
+}
+
+func (a *testAlerter) Resolve(res reservation) bool {
+	a.resolved = append(a.resolved, res)
+	return true
+}
+
+func (a *testAlerter) Name() string {
+	return "test"
+}
+
+func (a *testAlerter) Bootstrap() {}
+
+// setupMonitoring swaps the monitoring globals for a test alerter and puts them back when the test is done
+func setupMonitoring(t *testing.T, isCoordinator bool) *testAlerter {
+	test := &testAlerter{}
+	oldFuncs, oldCoordinator, oldCfg := alertFuncs, coordinator, cfg
+	alertFuncs = []alerter{test}
+	coordinator = isCoordinator
+	cfg = Config{}
+	cfg.Main.HoursBetweenAlerts = 1
+	t.Cleanup(func() {
+		alertFuncs, coordinator, cfg = oldFuncs, oldCoordinator, oldCfg
+	})
+	return test
+}
+
+func Test_jobChecker(t *testing.T) {
+	oldTime := int64(1403253684) // 6/20/2014
+	curTime := time.Now().UTC().Unix()
+
+	tests := []struct {
+		name        string
+		coordinator bool
+		app         int64
+		gotel       int64
+		alerted     []string
+	}{
+		{"coordinator all good", true, curTime, curTime, nil},
+		{"coordinator late app", true, oldTime, curTime, []string{"jimtest/monitor"}},
+		{"worker ignores apps", false, oldTime, curTime, nil},
+		{"worker watches coordinator", false, curTime, oldTime, []string{"gotel/coordinator"}},
+	}
+
+	for _, tt := range tests {
+		alerter := setupMonitoring(t, tt.coordinator)
+		_, store := newTestEndpoint(t)
+		store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, tt.app)
+		store.StoreCheckin(checkin{App: "gotel", Component: "coordinator"}, tt.gotel)
+
+		// the second run should be deduped by HoursBetweenAlerts
+		jobChecker(store)
+		jobChecker(store)
+
+		if len(alerter.alerts) != len(tt.alerted) {
+			t.Fatalf("%s: expected %d alerts got %d", tt.name, len(tt.alerted), len(alerter.alerts))
+		}
+		for i, res := range alerter.alerts {
+			if res.App+"/"+res.Component != tt.alerted[i] {
+				t.Fatalf("%s: expected alert for %s got %s/%s", tt.name, tt.alerted[i], res.App, res.Component)
+			}
+			if res.AlertMessage == "" {
+				t.Fatalf("%s: alert message was not formatted", tt.name)
+			}
+		}
+		if len(store.alerts) != len(tt.alerted) {
+			t.Fatalf("%s: expected %d stored alerts got %d", tt.name, len(tt.alerted), len(store.alerts))
+		}
+
+		// every run checks the node itself in
+		mode := "worker"
+		if tt.coordinator {
+			mode = "coordinator"
+		}
+		res, _ := store.GetReservation("gotel", mode)
+		if res.LastCheckin < curTime {
+			t.Fatalf("%s: job run was not stored for %s", tt.name, mode)
+		}
+	}
+}
+
+func Test_cleanUp(t *testing.T) {
+	store := newMemStore(Config{})
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -31).Unix()
+
+	store.LogHouseKeeping(checkin{App: "jimtest", Component: "monitor", Notes: "old"}, old)
+	store.LogHouseKeeping(checkin{App: "jimtest", Component: "monitor", Notes: "new"}, now.Unix())
+	store.alerts = append(store.alerts, alertEntry{App: "jimtest", Component: "monitor", AlertTime: old},
+		alertEntry{App: "jimtest", Component: "monitor", AlertTime: now.Unix()})
+
+	cleanUp(store, 30)
+
+	if len(store.houseKeeping) != 1 || store.houseKeeping[0].Notes != "new" {
+		t.Fatalf("Expected only the new housekeeping entry to survive [%v]", store.houseKeeping)
+	}
+	if len(store.alerts) != 1 || store.alerts[0].AlertTime != now.Unix() {
+		t.Fatalf("Expected only the new alert to survive [%v]", store.alerts)
+	}
+}
+
+func Test_scheduledSLA(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		last     string
+		due      string
+	}{
+		{"friday run is due monday", "", "2014-06-20T02:20:00Z", "2014-06-23T02:15:00Z"},
+		{"monday run is due tuesday", "UTC", "2014-06-23T02:20:00Z", "2014-06-24T02:15:00Z"},
+		{"schedule follows the timezone", "America/New_York", "2014-06-20T06:20:00Z", "2014-06-23T06:15:00Z"},
+	}
+
+	for _, tt := range tests {
+		last, _ := time.Parse(time.RFC3339, tt.last)
+		due, _ := time.Parse(time.RFC3339, tt.due)
+		res := reservation{
+			App:         "jimtest",
+			Component:   "nightly",
+			Schedule:    "15 2 * * 1-5",
+			Timezone:    tt.timezone,
+			LastCheckin: last.Unix(),
+		}
+		if !res.checkinDue().Equal(due) {
+			t.Fatalf("%s: expected checkin due at %v got %v", tt.name, due, res.checkinDue())
+		}
+		if !FailsSLA(res) {
+			t.Fatalf("%s: should have failed checkin SLA", tt.name)
+		}
+	}
+}
+
+func Test_graceSLA(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name   string
+		last   time.Duration
+		status string
+	}{
+		{"within frequency", 30 * time.Minute, slaOK},
+		{"late within grace", 65 * time.Minute, slaLate},
+		{"failing after grace", 75 * time.Minute, slaFailing},
+	}
+
+	for _, tt := range tests {
+		res := reservation{}
+		err := json.Unmarshal([]byte(`{"app": "jimtest", "component": "hourly", "frequency": 1, "time_units": "hours", "grace": "10m"}`), &res)
+		if err != nil {
+			t.Fatalf("%s: unable to decode reservation [%v]", tt.name, err)
+		}
+		res.LastCheckin = now.Add(-tt.last).Unix()
+		if status := slaStatus(res, now); status != tt.status {
+			t.Fatalf("%s: expected %s got %s", tt.name, tt.status, status)
+		}
+		if FailsSLA(res) != (tt.status == slaFailing) {
+			t.Fatalf("%s: FailsSLA should only be true once the grace period is over", tt.name)
+		}
+	}
+}
+
+func Test_maxRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		started time.Duration
+		snoozed bool
+		alerts  int
+	}{
+		{"not running", 0, false, 0},
+		{"within max runtime", 10 * time.Minute, false, 0},
+		{"over max runtime", 45 * time.Minute, false, 1},
+		{"over max runtime while snoozed", 45 * time.Minute, true, 0},
+	}
+
+	for _, tt := range tests {
+		test := setupMonitoring(t, true)
+		store := newMemStore(Config{})
+		store.StoreReservation(&reservation{App: "jimtest", Component: "cleanup", Frequency: 1, TimeUnits: "hours",
+			MaxRuntime: duration(30 * time.Minute)})
+		store.StoreCheckin(checkin{App: "jimtest", Component: "cleanup"}, time.Now().UTC().Unix())
+		if tt.started > 0 {
+			store.StoreStart(checkin{App: "jimtest", Component: "cleanup"}, time.Now().UTC().Add(-tt.started).Unix())
+		}
+		if tt.snoozed {
+			store.StoreSnooze(&snooze{App: "jimtest", Component: "cleanup", Start: time.Now().UTC().Unix(),
+				End: time.Now().UTC().Add(time.Hour).Unix()})
+		}
+
+		jobChecker(store)
+
+		if len(test.alerts) != tt.alerts {
+			t.Fatalf("%s: expected %d alerts got %d", tt.name, tt.alerts, len(test.alerts))
+		}
+		if tt.alerts > 0 && test.alerts[0].AlertType != alertMaxRuntime {
+			t.Fatalf("%s: expected a %s alert got %s", tt.name, alertMaxRuntime, test.alerts[0].AlertType)
+		}
+	}
+}
+
+func Test_resolveAlerts(t *testing.T) {
+	test := setupMonitoring(t, true)
+	_, store := newTestEndpoint(t)
+	store.StoreCheckin(checkin{App: "gotel", Component: "coordinator"}, time.Now().UTC().Unix())
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1403253684)
+
+	jobChecker(store)
+	if len(test.alerts) != 1 || len(test.resolved) != 0 {
+		t.Fatalf("Expected one alert and no resolves got %d and %d", len(test.alerts), len(test.resolved))
+	}
+
+	// checking in again recovers the reservation, that gets resolved once
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, time.Now().UTC().Unix())
+	jobChecker(store)
+	jobChecker(store)
+	if len(test.resolved) != 1 {
+		t.Fatalf("Expected one resolve got %d", len(test.resolved))
+	}
+	res := test.resolved[0]
+	if res.App != "jimtest" || res.AlertType != alertMissedCheckin || !strings.Contains(res.AlertMessage, "recovered") {
+		t.Fatalf("Unexpected resolve [%+v]", res)
+	}
+
+	// a new failure alerts straight away instead of waiting out HoursBetweenAlerts
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1403253684)
+	jobChecker(store)
+	if len(test.alerts) != 2 {
+		t.Fatalf("Expected the next failure to alert got %d alerts", len(test.alerts))
+	}
+}
+
+func Test_alertDedupeIsStored(t *testing.T) {
+	test := setupMonitoring(t, true)
+	_, store := newTestEndpoint(t)
+	store.StoreCheckin(checkin{App: "gotel", Component: "coordinator"}, time.Now().UTC().Unix())
+	store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1403253684)
+
+	// another node, or this one before a restart, already sent the alert
+	res, _ := store.GetReservation("jimtest", "monitor")
+	res.AlertType = alertMissedCheckin
+	store.StoreAlert(res, []string{test.Name()})
+
+	jobChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("Expected the stored alert to dedupe, got %d alerts", len(test.alerts))
+	}
+	if res, _ = store.GetReservation("jimtest", "monitor"); res.LastAlert == 0 {
+		t.Fatalf("Last alert time was not stored on the reservation")
+	}
+}
+
+type otherAlerter struct {
+	testAlerter
+}
+
+func (a *otherAlerter) Name() string {
+	return "other"
+}
+
+func Test_alerterRouting(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []alerterRoute
+		test   int
+		other  int
+	}{
+		{"no routes uses every alerter", nil, 1, 1},
+		{"routed to one alerter", []alerterRoute{{Name: "Other", Target: "team@example.com"}}, 0, 1},
+	}
+
+	for _, tt := range tests {
+		test := setupMonitoring(t, true)
+		other := &otherAlerter{}
+		alertFuncs = append(alertFuncs, other)
+		store := newMemStore(Config{})
+		store.StoreReservation(&reservation{App: "jimtest", Component: "monitor", Frequency: 5, TimeUnits: "minutes",
+			Alerters: tt.routes})
+		res, _ := store.GetReservation("jimtest", "monitor")
+
+		sendAlerts(store, res, alertMissedCheckin, defaultAlertMessage)
+
+		if len(test.alerts) != tt.test || len(other.alerts) != tt.other {
+			t.Fatalf("%s: expected %d/%d alerts got %d/%d", tt.name, tt.test, tt.other, len(test.alerts), len(other.alerts))
+		}
+		if tt.routes != nil && other.alerts[0].alerterTarget(other.Name()) != "team@example.com" {
+			t.Fatalf("%s: route target was not passed to the alerter", tt.name)
+		}
+	}
+}
+
+func Test_snoozedSLA(t *testing.T) {
+	now := time.Now().UTC()
+	res := reservation{App: "jimtest", Component: "monitor", Frequency: 5, TimeUnits: "minutes",
+		LastCheckin: now.Add(-time.Hour).Unix()}
+
+	tests := []struct {
+		name   string
+		until  time.Duration
+		status string
+	}{
+		{"not snoozed", 0, slaFailing},
+		{"snoozed", time.Hour, slaSnoozed},
+		{"snooze just ended", -time.Minute, slaOK},
+		{"snooze ended a while ago", -time.Hour, slaFailing},
+	}
+
+	for _, tt := range tests {
+		res.SnoozedUntil = 0
+		if tt.until != 0 {
+			res.SnoozedUntil = now.Add(tt.until).Unix()
+		}
+		if status := slaStatus(res, now); status != tt.status {
+			t.Fatalf("%s: expected %s got %s", tt.name, tt.status, status)
+		}
+	}
+}
+
+func Test_mapKey(t *testing.T) {
+	a := reservation{App: "ab", Component: "c", AlertType: alertMissedCheckin}
+	b := reservation{App: "a", Component: "bc", AlertType: alertMissedCheckin}
+	if a.mapKey("") == b.mapKey("") || a.mapKey("test") == b.mapKey("test") {
+		t.Fatalf("Expected different reservations to have different keys [%q]", a.mapKey(""))
+	}
+}
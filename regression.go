@@ -0,0 +1,117 @@
+package gotel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// alertDegraded is sent when a component still checks in on time but its recent runs take far longer than usual
+const alertDegraded = "degraded"
+
+// runtimeStats holds the numbers a regression check was decided on, all in seconds
+type runtimeStats struct {
+	Median   int64
+	P95      int64
+	Degraded bool
+}
+
+// checkRuntimes compares the median of the newest recent run times against the p95 of the older ones. runtimes are
+// newest first, nothing is flagged until there are at least minBaseline older runs to compare with.
+func checkRuntimes(runtimes []int64, recent, minBaseline int) (runtimeStats, bool) {
+	if recent < 1 || len(runtimes) < recent+minBaseline || len(runtimes) == recent {
+		return runtimeStats{}, false
+	}
+	stats := runtimeStats{
+		Median: median(runtimes[:recent]),
+		P95:    percentile(runtimes[recent:], 95),
+	}
+	stats.Degraded = stats.Median > stats.P95
+	return stats, true
+}
+
+func median(values []int64) int64 {
+	sorted := sortedCopy(values)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// percentile uses the nearest rank method
+func percentile(values []int64, p float64) int64 {
+	sorted := sortedCopy(values)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func sortedCopy(values []int64) []int64 {
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// regressionChecker looks for components whose recent runs have drifted well past their own history and raises
+// a degraded alert for them, even though they're still checking in on time
+func regressionChecker(store Store) {
+	recent := cfg.Regression.RecentRuns
+	if recent == 0 {
+		recent = 5
+	}
+	days := cfg.Regression.BaselineDays
+	if days == 0 {
+		days = 30
+	}
+	minBaseline := cfg.Regression.MinBaselineRuns
+	if minBaseline == 0 {
+		minBaseline = 10
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days).Unix()
+
+	reservations, err := store.ListReservations()
+	if err != nil {
+		l.err("Unable to run regression checker [%v]", err)
+		return
+	}
+	now := time.Now().UTC()
+	if err := applySnoozes(store, reservations, now); err != nil {
+		l.err("Unable to run regression checker [%v]", err)
+		return
+	}
+	windows, err := store.ListMaintenance()
+	if err != nil {
+		l.err("Unable to run regression checker [%v]", err)
+		return
+	}
+	for _, res := range reservations {
+		runtimes, err := store.ListRuntimes(res.App, res.Component, since)
+		if err != nil {
+			l.err("Unable to list run times for [%s/%s] [%v]", res.App, res.Component, err)
+			continue
+		}
+		stats, ok := checkRuntimes(runtimes, recent, minBaseline)
+		if !ok {
+			continue
+		}
+		if !stats.Degraded {
+			resolveAlerts(store, res, alertDegraded)
+			continue
+		}
+		l.info("App degraded [%s/%s] median of last %d runs %ds, %d day p95 %ds", res.App, res.Component, recent,
+			stats.Median, days, stats.P95)
+		if res.SnoozedUntil > now.Unix() || inMaintenance(windows, res, now) {
+			l.info("App is snoozed or in a maintenance window [%s/%s], not alerting", res.App, res.Component)
+			continue
+		}
+		message := fmt.Sprintf("App: [{app}] Component: [{component}] is degraded. The median of its last %d runs took %v "+
+			"against a %d day p95 of %v. Contact owner [{owner}]", recent, time.Duration(stats.Median)*time.Second, days,
+			time.Duration(stats.P95)*time.Second)
+		sendAlerts(store, res, alertDegraded, message)
+	}
+}
@@ -0,0 +1,88 @@
+package gotel
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_checkRuntimes(t *testing.T) {
+	baseline := []int64{60, 62, 58, 61, 59, 60, 63, 57, 60, 90}
+	tests := []struct {
+		name     string
+		runtimes []int64
+		checked  bool
+		degraded bool
+	}{
+		{"not enough history", append([]int64{300, 300, 300, 300, 300}, 60, 60), false, false},
+		{"steady", append([]int64{61, 59, 60, 62, 58}, baseline...), true, false},
+		{"one slow run", append([]int64{300, 59, 60, 62, 58}, baseline...), true, false},
+		{"degraded", append([]int64{120, 130, 59, 125, 140}, baseline...), true, true},
+	}
+
+	for _, tt := range tests {
+		stats, checked := checkRuntimes(tt.runtimes, 5, 10)
+		if checked != tt.checked {
+			t.Fatalf("%s: expected checked %v got %v", tt.name, tt.checked, checked)
+		}
+		if stats.Degraded != tt.degraded {
+			t.Fatalf("%s: expected degraded %v got %v (median %d p95 %d)", tt.name, tt.degraded, stats.Degraded,
+				stats.Median, stats.P95)
+		}
+	}
+}
+
+func Test_regressionChecker(t *testing.T) {
+	test := setupMonitoring(t, true)
+	store := newMemStore(Config{})
+	store.StoreReservation(&reservation{App: "jimtest", Component: "monitor", Frequency: 5, TimeUnits: "minutes"})
+
+	now := time.Now().UTC()
+	logRun := func(runtime time.Duration, ago time.Duration, status string) {
+		finished := now.Add(-ago)
+		store.LogHouseKeeping(checkin{App: "jimtest", Component: "monitor", Status: status,
+			Started: finished.Add(-runtime).Unix()}, finished.Unix())
+	}
+	for i := 20; i > 5; i-- {
+		logRun(time.Minute, time.Duration(i)*time.Hour, checkinSuccess)
+	}
+	regressionChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("expected no alerts for steady runs got %d", len(test.alerts))
+	}
+
+	// failed runs don't count towards the run times
+	for i := 5; i > 0; i-- {
+		logRun(5*time.Minute, time.Duration(i)*time.Hour, checkinFail)
+	}
+	regressionChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("expected no alerts for slow failed runs got %d", len(test.alerts))
+	}
+
+	for i := 50; i > 0; i -= 10 {
+		logRun(5*time.Minute, time.Duration(i)*time.Minute, checkinSuccess)
+	}
+	snooze := &snooze{App: "jimtest", Component: "monitor", Start: now.Add(-time.Minute).Unix(), End: now.Add(time.Hour).Unix()}
+	store.StoreSnooze(snooze)
+	regressionChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("expected no alerts while snoozed got %d", len(test.alerts))
+	}
+	store.CancelSnooze(snooze.ID, now.Unix())
+
+	window := &maintenance{App: "jimtest", Component: "monitor", Schedule: "* * * * *", Duration: duration(2 * time.Minute)}
+	if err := validateMaintenance(window); err != nil {
+		t.Fatalf("Expected a valid window [%v]", err)
+	}
+	store.StoreMaintenance(window)
+	regressionChecker(store)
+	if len(test.alerts) != 0 {
+		t.Fatalf("expected no alerts during maintenance got %d", len(test.alerts))
+	}
+	store.RemoveMaintenance(window.ID)
+
+	regressionChecker(store)
+	if len(test.alerts) != 1 || test.alerts[0].AlertType != alertDegraded {
+		t.Fatalf("expected one %s alert got %v", alertDegraded, test.alerts)
+	}
+}
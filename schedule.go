@@ -0,0 +1,39 @@
+package gotel
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// parseSchedule parses a standard 5 field cron expression (or a descriptor like @daily) and the timezone it
+// should be evaluated in, an empty timezone means UTC
+func parseSchedule(spec, timezone string) (cron.Schedule, *time.Location, error) {
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sched, loc, nil
+}
+
+// checkinDue returns when the next checkin is expected after the last one. Scheduled reservations expect the
+// first run of their schedule after the last checkin, everything else expects one Frequency later.
+func (res reservation) checkinDue() time.Time {
+	// a snooze holds off the next checkin until the window is over
+	last := time.Unix(res.LastCheckin, 0).UTC()
+	if res.SnoozedUntil > res.LastCheckin {
+		last = time.Unix(res.SnoozedUntil, 0).UTC()
+	}
+	if res.Schedule != "" {
+		sched, loc, err := parseSchedule(res.Schedule, res.Timezone)
+		if err == nil {
+			return sched.Next(last.In(loc)).UTC()
+		}
+		l.warn("Invalid schedule [%s] for [%s/%s] falling back to frequency [%v]", res.Schedule, res.App, res.Component, err)
+	}
+	return last.Add(time.Duration(getSecondsFromUnits(res.Frequency, res.TimeUnits)) * time.Second)
+}
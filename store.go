@@ -1,39 +1,174 @@
 package gotel
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 )
 
-// Endpoint holds the reference to our DB connection
+// Endpoint holds the reference to our storage backend
 type Endpoint struct {
-	Db *sql.DB
+	Store Store
+}
+
+// Store is the storage backend GoTel keeps its reservations, checkins, snoozes, alerts and nodes in.
+// Everything outside of the backend implementations should only talk to the DB through this interface.
+type Store interface {
+	// StoreReservation inserts a reservation or updates an existing one for the same app/component
+	StoreReservation(r *reservation) (bool, error)
+	// ListReservations returns every reservation, most recent checkin first
+	ListReservations() ([]reservation, error)
+	// GetReservation returns a single reservation by app and component
+	GetReservation(app, component string) (reservation, error)
+	// StoreCheckin bumps the last checkin time and checkin count of a reservation and ends any run in progress
+	StoreCheckin(c checkin, now int64) (bool, error)
+	// LogHouseKeeping records a checkin in the checkin history, along with the run time when the run was started
+	LogHouseKeeping(c checkin, now int64) (bool, error)
+	// ListHouseKeeping returns up to limit of the latest checkins in a reservation's checkin history, newest first
+	ListHouseKeeping(app, component string, limit int) ([]houseKeepingEntry, error)
+	// ListRuntimes returns the run times in seconds of the successful runs logged since the given unix time, newest first
+	ListRuntimes(app, component string, since int64) ([]int64, error)
+	// StoreFailure records a failed run against a reservation, it doesn't count as a checkin so the SLA keeps running
+	StoreFailure(c checkin, now int64) (bool, error)
+	// StoreStart marks a reservation as having a run in progress, false when there's no such reservation
+	StoreStart(c checkin, now int64) (bool, error)
+	// StoreCheckOut removes a reservation
+	StoreCheckOut(c *checkOut) (bool, error)
+	// StoreAck records who acknowledged a failing reservation
+	StoreAck(a *ack, now int64) (bool, error)
+	// ClearAck removes the acknowledgement once the reservation has recovered
+	ClearAck(app, component string) error
+	// StoreSnooze stores a snooze window for a reservation and sets its ID
+	StoreSnooze(p *snooze) (bool, error)
+	// ListSnoozes returns the snooze windows that end at or after the given unix time and haven't been cancelled
+	ListSnoozes(since int64) ([]snooze, error)
+	// CancelSnooze cancels a snooze window that hasn't ended yet, false if there was no such window
+	CancelSnooze(id int, now int64) (bool, error)
+	// StoreAlert records that alerters fired the reservation's alert type
+	StoreAlert(res reservation, alerters []string) error
+	// LastAlertTime returns when the alerter last fired the reservation's alert type without it being resolved, 0 if never
+	LastAlertTime(res reservation, alerterName string) (int64, error)
+	// FiringAlerters returns the alerters with unresolved alerts of the reservation's alert type
+	FiringAlerters(res reservation) ([]string, error)
+	// ResolveAlert marks the alerter's alerts of the reservation's alert type as resolved
+	ResolveAlert(res reservation, alerterName string) error
+	// GetEscalation returns how far the reservation's alert type has escalated, Started is 0 when it isn't escalating
+	GetEscalation(res reservation) (escalation, error)
+	// StoreEscalation saves how far the reservation's alert type has escalated
+	StoreEscalation(res reservation, e escalation) error
+	// ClearEscalation forgets the reservation's alert type escalation once it has recovered
+	ClearEscalation(res reservation) error
+	// StoreMaintenance stores a recurring maintenance window and sets its ID
+	StoreMaintenance(m *maintenance) (bool, error)
+	// ListMaintenance returns every maintenance window
+	ListMaintenance() ([]maintenance, error)
+	// RemoveMaintenance deletes a maintenance window, false if there was no such window
+	RemoveMaintenance(id int) (bool, error)
+	// ListBadGuests returns the number of alerts per app/component, worst offenders first
+	ListBadGuests() ([]badGuest, error)
+	// ListNodes returns the registered GoTel nodes
+	ListNodes() ([]node, error)
+	// InsertNode registers a GoTel node, registering the same ip twice is not an error
+	InsertNode(ipAddress string, nodeID int) error
+	// RemoveNode unregisters a GoTel node
+	RemoveNode(ipAddress string) error
+	// HasLock tries to acquire the coordinator lock
+	HasLock() bool
+	// ReleaseLock releases the coordinator lock
+	ReleaseLock() (bool, error)
+	// CleanUp removes checkin history, alerts and snoozes older than the given unix time
+	CleanUp(before int64) error
+	// Close releases the underlying connection
+	Close() error
 }
 
 // reservation is when an app first registers that it will be checking into our gotel
 type reservation struct {
-	JobID                int    `json:"job_id"`
-	Owner                string `json:"owner"`
-	Notify               string `json:"notify"`
-	AlertMessage         string `json:"alert_msg"`
-	App                  string `json:"app"`
-	Component            string `json:"component"`
-	Frequency            int    `json:"frequency"`
-	TimeUnits            string `json:"time_units"`
-	LastCheckin          int64  `json:"last_checkin"`
-	LastCheckinStr       string `json:"last_checkin_str"` // human readable time
-	TimeSinceLastCheckin string `json:"time_since_last_checkin"`
-	FailingSLA           bool   `json:"failing_sla"`
-	NumCheckins          int    `json:"number_of_checkins"`
+	JobID                int      `json:"job_id"`
+	Owner                string   `json:"owner"`
+	Notify               string   `json:"notify"`
+	AlertMessage         string   `json:"alert_msg"`
+	App                  string   `json:"app"`
+	Component            string   `json:"component"`
+	Frequency            int      `json:"frequency"`
+	TimeUnits            string   `json:"time_units"`
+	Schedule             string   `json:"schedule"`    // cron expression, replaces Frequency/TimeUnits when set
+	Timezone             string   `json:"timezone"`    // timezone the schedule runs in, defaults to UTC
+	Grace                duration `json:"grace"`       // how long after a missed checkin the job is late before it fails
+	MaxRuntime           duration `json:"max_runtime"` // how long a started run may take before alerting
+	RunStarted           int64    `json:"run_started"` // unix time the run in progress started, 0 when not running
+	RunningFor           string   `json:"running_for,omitempty"`
+	LastCheckin          int64    `json:"last_checkin"`
+	LastAlert            int64    `json:"last_alert"`       // unix time of the last alert sent, 0 if never
+	LastCheckinStr       string   `json:"last_checkin_str"` // human readable time
+	TimeSinceLastCheckin string   `json:"time_since_last_checkin"`
+	FailingSLA           bool     `json:"failing_sla"`
+	Late                 bool     `json:"late"` // missed its checkin but is still within the grace period
+	NumCheckins          int      `json:"number_of_checkins"`
+	LastStatus           string   `json:"last_status,omitempty"` // status of the last reported run, success or fail
+	LastExitCode         int      `json:"last_exit_code,omitempty"`
+	LastOutput           string   `json:"last_output,omitempty"`
+	AlertType            string   `json:"alert_type,omitempty"` // what the alert being sent is about
+	// Alerters limits which alerters the reservation alerts through, all of them when empty
+	Alerters []alerterRoute `json:"alerters,omitempty"`
+	// Escalation names the escalation policy to alert through instead of Alerters
+	Escalation string `json:"escalation,omitempty"`
+	// AckedBy is who acknowledged the current failure, alerts stop until the reservation recovers
+	AckedBy        string `json:"acked_by,omitempty"`
+	AckedTimestamp int64  `json:"acked_timestamp,omitempty"`
+	AckedStr       string `json:"acked_str,omitempty"` // human readable ack time
+	// SnoozedUntil is the end of the latest snooze window that has started, checkins are due from then on
+	SnoozedUntil int64  `json:"snoozed_until,omitempty"`
+	SnoozedStr   string `json:"snoozed_str,omitempty"` // human readable end of an active snooze
+	// Severity is passed to alerters that have one, e.g. PagerDuty's critical, error, warning or info
+	Severity string `json:"severity,omitempty"`
+	// EmailSubject is a template overriding the SMTP alerter's subject for this reservation
+	EmailSubject string `json:"email_subject,omitempty"`
+	// Tags let maintenance windows cover groups of reservations
+	Tags          []string `json:"tags,omitempty"`
+	InMaintenance bool     `json:"in_maintenance,omitempty"`
+}
+
+// alerterRoute sends a reservation's alerts to an alerter, the target overrides where that alerter sends them,
+// e.g. the email addresses for SMTP or the service key for PagerDuty
+type alerterRoute struct {
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"`
 }
 
-// checkin holds a struct that is populated when an app checks in as still alive
+// checkin holds a struct that is populated when an app checks in as still alive. A checkin that follows a /start
+// completes that run, jobs that didn't call /start can pass the unix time they started themselves.
 type checkin struct {
 	App       string `json:"app"`
 	Component string `json:"component"`
 	Notes     string `json:"notes"`
+	Started   int64  `json:"started,omitempty"`
+	Status    string `json:"status,omitempty"` // success or fail, empty means success
+	ExitCode  int    `json:"exit_code,omitempty"`
+	Output    string `json:"output,omitempty"` // tail of the job's stdout/stderr
+}
+
+const (
+	checkinSuccess = "success"
+	checkinFail    = "fail"
+)
+
+// failed says whether the job reported that its run failed
+func (c checkin) failed() bool {
+	return c.Status == checkinFail
+}
+
+// houseKeepingEntry is a checkin from the checkin history
+type houseKeepingEntry struct {
+	App         string `json:"app"`
+	Component   string `json:"component"`
+	Notes       string `json:"notes"`
+	LastCheckin int64  `json:"last_checkin"`
+	Started     int64  `json:"started,omitempty"`
+	Runtime     int64  `json:"runtime,omitempty"` // seconds, when the run's start is known
+	Status      string `json:"status"`
+	ExitCode    int    `json:"exit_code,omitempty"`
+	Output      string `json:"output,omitempty"`
 }
 
 // checkOut is for removing reservations
@@ -42,137 +177,87 @@ type checkOut struct {
 	Component string `json:"component"`
 }
 
-// snooze holds a struct for when users want to pause an alert for maintenance
+// ack is a user acknowledging a reservation's failure, no more alerts are sent for it until it recovers
+type ack struct {
+	App       string `json:"app"`
+	Component string `json:"component"`
+	AckedBy   string `json:"acked_by"`
+}
+
+// snooze is a window during which a reservation's missed checkins don't alert
 type snooze struct {
+	ID        int    `json:"id"`
 	App       string `json:"app"`
 	Component string `json:"component"`
 	Duration  int    `json:"duration"`
 	TimeUnits string `json:"time_units"`
+	Start     int64  `json:"start"` // unix time the window starts, defaults to now
+	End       int64  `json:"end"`
+	Reason    string `json:"reason"`
+	CreatedBy string `json:"created_by"`
 }
 
-// InitDb initializes and then bootstraps the database
-func InitDb(host, user, pass string, conf Config) *sql.DB {
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:3306)/gotel", user, pass, host))
-	if err != nil {
-		panic(err)
-	}
-	err = db.Ping()
-	if err != nil {
-		panic(fmt.Sprintf("Unable to ping the DB at host [%s] user [%s]: %v", host, user, err))
-	}
-	bootstrapDb(db, conf)
-	return db
+// DbConfig holds the settings needed to reach the backing database
+type DbConfig struct {
+	// Driver is the database/sql driver to use, mysql, postgres or sqlite3. memory keeps everything in process.
+	Driver string
+	Host   string
+	User   string
+	Pass   string
+	// Path is the database file when running on sqlite3
+	Path string
 }
 
-func storeReservation(db *sql.DB, r *reservation) (bool, error) {
-
-	// get current unix time one day into the future as the initial insert data, give it one day to bake
-	tomorrow := time.Now().Add(24 * time.Hour).UTC().Unix()
-	now := time.Now().UTC().Unix()
-
-	seconds := getSecondsFromUnits(r.Frequency, r.TimeUnits)
-	if seconds < 10 {
-		return false, errors.New("unable to store reservations for less than 10 seconds at this time, for no real reason")
-	}
-
-	stmt, err := db.Prepare(`INSERT INTO reservations(app, component, owner, notify, alert_msg, frequency, time_units, inserted_timestamp, last_checkin_timestamp)
-		VALUES (?,?,?,?,?,?,?,?,?)
-		ON DUPLICATE KEY UPDATE notify=?, alert_msg=?, frequency=?, time_units=?
-		`)
-
-	if err != nil {
-		l.warn("unable to prepare statement %s", err)
-		return false, errors.New("Unable to save record")
-	}
-	defer stmt.Close()
-
-	res, err := stmt.Exec(r.App, r.Component, r.Owner, r.Notify, r.AlertMessage, r.Frequency, r.TimeUnits, now, tomorrow,
-		r.Notify, r.AlertMessage, r.Frequency, r.TimeUnits)
-	if err != nil {
-		l.warn("Unable to insert record %s", err)
-		return false, errors.New("Unable to save record")
-	}
-
-	rowCnt, err := res.RowsAffected()
-	if err != nil {
-		return false, errors.New("Unable to save record")
+// InitDb initializes and then bootstraps the database
+func InitDb(dbc DbConfig, conf Config) Store {
+	if dbc.Driver == "memory" {
+		l.warn("Using the in memory store, nothing will survive a restart")
+		return newMemStore(conf)
 	}
-
-	l.info("Insertedaffected = %d\n", rowCnt)
-	return true, nil
-
+	s := openSQLStore(dbc)
+	bootstrapDb(s, conf)
+	return s
 }
 
-func logHouseKeeping(db *sql.DB, c checkin, now int64) (bool, error) {
-
-	//Insert
-	stmt, err := db.Prepare("INSERT INTO housekeeping(app, component, notes, last_checkin_timestamp) VALUES (?, ?, ?, ?)")
-	if err != nil {
-		l.warn("Unable to prepare record %s", err)
-		return false, errors.New("Unable to store checkin")
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(c.App, c.Component, c.Notes, now)
-	if err != nil {
-		l.warn("Unable to insert record %s", err)
-		return false, errors.New("Unable to store checkin")
+// OpenDb connects to the database without touching the schema, for running migrations by hand
+func OpenDb(dbc DbConfig) Store {
+	if dbc.Driver == "memory" {
+		return newMemStore(Config{})
 	}
-
-	return true, nil
+	return openSQLStore(dbc)
 }
 
-func storeCheckin(db *sql.DB, c checkin, now int64) (bool, error) {
-
-	stmt, err := db.Prepare("UPDATE reservations SET last_checkin_timestamp = ?, num_checkins = num_checkins + 1 WHERE app=? AND component=?")
-	if err != nil {
-		l.warn("Unable to prepare record %s", err)
-		return false, errors.New("Unable to prepare checkin")
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(now, c.App, c.Component)
+func openSQLStore(dbc DbConfig) *sqlStore {
+	d := newDialect(dbc)
+	db, err := d.open(dbc)
 	if err != nil {
-		l.warn("Unable to update reservation %s", err)
-		return false, errors.New("Unable to store checkin")
+		panic(err)
 	}
-
-	return true, nil
+	return &sqlStore{db: db, dialect: d}
 }
 
-func storeCheckOut(db *sql.DB, c *checkOut) (bool, error) {
-
-	stmt, err := db.Prepare("DELETE FROM reservations WHERE app=? AND component=?")
-	if err != nil {
-		l.warn("Unable to prepare record %s", err)
-		return false, errors.New("Unable to prepare checkin")
-	}
-	defer stmt.Close()
-	_, err = stmt.Exec(c.App, c.Component)
-	if err != nil {
-		l.warn("Unable to update reservation %s", err)
-		return false, errors.New("Unable to store checkin")
+func newDialect(dbc DbConfig) dialect {
+	switch dbc.Driver {
+	case "", "mysql":
+		return &mysqlDialect{}
+	case "postgres":
+		return &postgresDialect{}
+	case "sqlite3":
+		return &sqliteDialect{}
 	}
-	return true, nil
+	panic(fmt.Sprintf("Unsupported DB driver [%s]", dbc.Driver))
 }
 
-func storeSnooze(db *sql.DB, p *snooze) (bool, error) {
-	futureSeconds := getSecondsFromUnits(p.Duration, p.TimeUnits)
-
-	pausedTime := time.Now().Add(time.Duration(futureSeconds) * time.Second).UTC().Unix()
-
-	stmt, err := db.Prepare("UPDATE reservations SET last_checkin_timestamp = ? WHERE app=? AND component=?")
-	if err != nil {
-		l.warn("Unable to prepare record %s", err)
-		return false, errors.New("Unable to prepare snooze")
+// checkFrequency rejects interval reservations that are too short, scheduled ones are checked by the API
+func checkFrequency(r *reservation) error {
+	if r.Schedule != "" {
+		return nil
 	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(pausedTime, p.App, p.Component)
-	if err != nil {
-		l.warn("Unable to update snooze %s", err)
-		return false, errors.New("Unable to store snooze")
+	seconds := getSecondsFromUnits(r.Frequency, r.TimeUnits)
+	if seconds < 10 {
+		return errors.New("unable to store reservations for less than 10 seconds at this time, for no real reason")
 	}
-
-	return true, nil
+	return nil
 }
 
 func getSecondsFromUnits(freq int, units string) int {
@@ -189,206 +274,37 @@ func getSecondsFromUnits(freq int, units string) int {
 	return seconds
 }
 
-func bootstrapDb(db *sql.DB, conf Config) {
+// bootstrapDb brings the schema up to date and makes sure GoTel is monitoring itself. It refuses to start on a
+// schema that a newer GoTel has migrated past what this binary understands.
+func bootstrapDb(s *sqlStore, conf Config) {
 
 	l.info("Bootstrapping GoTel DB tables")
-	versions := getTablesVersions(db)
-	tx, err := db.Begin()
+	err := s.prepareMigrations()
 	if err != nil {
-		l.err("Could not start transaction [%v]", err)
+		l.err("Could not prepare migrations [%v]", err)
 		panic(err)
 	}
-
-	if ver, hasTable := versions["tables_versions"]; !hasTable {
-		doTxQuery(tx, `CREATE TABLE IF NOT EXISTS tables_versions (
-		  id int(11) unsigned NOT NULL AUTO_INCREMENT,
-		  table_name varchar(30) NOT NULL,
-		  table_version int(11) NOT NULL DEFAULT 0,
-		  PRIMARY KEY (id),
-		  UNIQUE KEY uniq_name (table_name)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8;`)
-		setTableVersion(tx, "tables_versions", 0)
-	} else {
-		l.info("tables_versions is version %d", ver)
-	}
-
-	if ver, hasTable := versions["alerts"]; !hasTable {
-		doTxQuery(tx, `CREATE TABLE IF NOT EXISTS alerts (
-		  id int(11) unsigned NOT NULL AUTO_INCREMENT,
-		  app varchar(30) DEFAULT NULL,
-		  component varchar(30) DEFAULT NULL,
-		  alert_time int(11) DEFAULT NULL,
-		  alerters text DEFAULT NULL,
-		  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-		  PRIMARY KEY (id)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8;`)
-		setTableVersion(tx, "alerts", 0)
-	} else {
-		l.info("alerts is version %d", ver)
-	}
-
-	if ver, hasTable := versions["reservations"]; !hasTable {
-		doTxQuery(tx, `CREATE TABLE IF NOT EXISTS reservations (
-		  id int(11) NOT NULL AUTO_INCREMENT,
-		  app varchar(150) DEFAULT NULL,
-		  component varchar(150) DEFAULT NULL,
-		  owner text DEFAULT NULL,
-		  notify text DEFAULT NULL,
-		  alert_msg text DEFAULT NULL,
-		  frequency int(11) DEFAULT NULL,
-		  time_units varchar(30) DEFAULT NULL,
-		  inserted_timestamp int(11) DEFAULT NULL,
-		  num_checkins int(11) DEFAULT '0',
-		  last_alert_timestamp int(11) DEFAULT NULL,
-		  last_checkin_timestamp int(11) DEFAULT NULL,
-		  PRIMARY KEY (id),
-		  UNIQUE KEY uniq_app (app,component)
-		) ENGINE=InnoDB AUTO_INCREMENT=2 DEFAULT CHARSET=utf8;`)
-		setTableVersion(tx, "reservations", 1)
-	} else {
-		l.info("reservations is version %d", ver)
-
-		if ver < 1 {
-			doTxQuery(tx, `ALTER TABLE reservations ADD COLUMN alert_msg text DEFAULT NULL AFTER notify;`)
-			setTableVersion(tx, "reservations", 1)
-		}
-	}
-
-	if ver, hasTable := versions["housekeeping"]; !hasTable {
-		doTxQuery(tx, `CREATE TABLE IF NOT EXISTS housekeeping (
-		  id int(11) NOT NULL AUTO_INCREMENT,
-		  app varchar(30) DEFAULT NULL,
-		  component varchar(30) DEFAULT NULL,
-		  notes text,
-		  last_checkin_timestamp int(11) DEFAULT NULL,
-		  insert_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-		  PRIMARY KEY (id)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8;`)
-		setTableVersion(tx, "housekeeping", 0)
-	} else {
-		l.info("housekeeping is version %d", ver)
-	}
-
-	if ver, hasTable := versions["nodes"]; !hasTable {
-		doTxQuery(tx, `CREATE TABLE IF NOT EXISTS nodes (
-		  id int(11) unsigned NOT NULL AUTO_INCREMENT,
-		  ip_address varchar(20) DEFAULT NULL,
-		  node_id int(30) DEFAULT NULL,
-		  PRIMARY KEY (id),
-		  UNIQUE KEY uniq_ip (ip_address)
-		) ENGINE=InnoDB AUTO_INCREMENT=7 DEFAULT CHARSET=utf8;`)
-		setTableVersion(tx, "nodes", 0)
-	} else {
-		l.info("nodes is version %d", ver)
+	err = s.migrateUp(0)
+	if err != nil {
+		l.err("Could not migrate the DB [%v]", err)
+		panic(err)
 	}
 
 	// store gotel as the initial application to monitor
 	l.info("Starting to bootstrap worker/coordinator reservations...")
 	tomorrow := time.Now().Add(24 * time.Hour).UTC().Unix()
 	now := time.Now().UTC().Unix()
-	gotelApp := `INSERT INTO reservations (app, component, owner, notify, frequency, time_units, inserted_timestamp, last_checkin_timestamp)
-		VALUES ('gotel', 'coordinator', ?, ?, 5, 'minutes', ?, ?) ON DUPLICATE KEY UPDATE owner=?`
-	_, err = tx.Exec(gotelApp, conf.Main.GotelOwnerEmail, conf.Main.GotelOwnerEmail, now, tomorrow, conf.Main.GotelOwnerEmail)
-	if err != nil {
-		l.warn("storing gotel/coordinator as initial app [%q]", err)
-	} else {
-		l.info("Inserted gotel/coordinator as first app to monitor")
-	}
-
-	gotelAppWorker := `INSERT INTO reservations (app, component, owner, notify, frequency, time_units, inserted_timestamp, last_checkin_timestamp)
-		VALUES ('gotel', 'worker', ?, ?, 5, 'minutes', ?, ?) ON DUPLICATE KEY UPDATE owner=?`
-	_, err = tx.Exec(gotelAppWorker, conf.Main.GotelOwnerEmail, conf.Main.GotelOwnerEmail, now, tomorrow, conf.Main.GotelOwnerEmail)
-	if err != nil {
-		l.warn("storing gotel/worker as initial app [%v]", err)
-	} else {
-		l.info("Inserted gotel/worker as first app to monitor")
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		l.err("Could not commit transaction [%v]", err)
-		panic(err)
-	}
-	l.info("DB ready")
-}
-
-func getTablesVersions(db *sql.DB) map[string]int {
-	var (
-		tbl string
-		ver int
-	)
-	versions := make(map[string]int)
-
-	rows, err := db.Query(`SELECT table_name FROM information_schema.tables WHERE table_schema='gotel'`)
-	if err != nil {
-		l.err("Unable to select tables [%v]", err)
-		panic(err)
-	}
-
-	for rows.Next() {
-		err = rows.Scan(&tbl)
-		if err != nil {
-			l.err("Unable to scan table rows [%v]", err)
-			panic(err)
-		}
-		versions[tbl] = 0
-	}
-	err = rows.Close()
-	if err != nil {
-		l.err("Unable to close query [%v]", err)
-	}
-
-	if _, hasKey := versions["tables_versions"]; hasKey {
-		rows, err = db.Query(`SELECT table_name, table_version FROM tables_versions`)
+	gotelApp := s.dialect.rebind(s.dialect.upsert("reservations",
+		[]string{"app", "component", "owner", "notify", "frequency", "time_units", "inserted_timestamp", "last_checkin_timestamp"},
+		[]string{"app", "component"},
+		[]string{"owner"}))
+	for _, component := range []string{"coordinator", "worker"} {
+		_, err = s.db.Exec(gotelApp, "gotel", component, conf.Main.GotelOwnerEmail, conf.Main.GotelOwnerEmail, 5, "minutes", now, tomorrow)
 		if err != nil {
-			l.err("Unable to select tables versions [%v]", err)
-			panic(err)
-		}
-
-		for rows.Next() {
-			err = rows.Scan(&tbl, &ver)
-			if err != nil {
-				l.err("Unable to scan table version [%v]", err)
-				panic(err)
-			}
-			versions[tbl] = ver
-		}
-		err = rows.Close()
-		if err != nil {
-			l.err("Unable to close query [%v]", err)
+			l.warn("storing gotel/%s as initial app [%v]", component, err)
+		} else {
+			l.info("Inserted gotel/%s as first app to monitor", component)
 		}
 	}
-
-	return versions
-}
-
-func setTableVersion(tx *sql.Tx, tbl string, ver int) {
-	tableVersion := `INSERT INTO tables_versions (table_name, table_version)
-		VALUES (?, ?) ON DUPLICATE KEY UPDATE table_version=?`
-	_, err := tx.Exec(tableVersion, tbl, ver, ver)
-	if err != nil {
-		l.err("Could not set table version [%v]", err)
-		err = tx.Rollback()
-		if err != nil {
-			l.err("Could not roll back transaction [%v]", err)
-		}
-		panic(err)
-	} else {
-		l.info("Updated %s to version %d", tbl, ver)
-	}
-}
-
-func doTxQuery(tx *sql.Tx, query string) sql.Result {
-	res, err := tx.Exec(query)
-
-	if err != nil {
-		l.err("Could not execute query [%v]", err)
-		err = tx.Rollback()
-		if err != nil {
-			l.err("Could not roll back transaction [%v]", err)
-		}
-		panic(err)
-	}
-
-	return res
+	l.info("DB ready")
 }
@@ -0,0 +1,509 @@
+package gotel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memStore is a Store that keeps everything in process memory. It's meant for tests and throwaway instances,
+// nothing survives a restart and there's no one else to elect a coordinator against.
+type memStore struct {
+	mu           sync.Mutex
+	lastID       int
+	reservations []reservation
+	houseKeeping []houseKeepingEntry
+	alerts       []alertEntry
+	escalations  map[string]escalation
+	snoozes      []snoozeEntry
+	lastSnoozeID int
+	maintenance  []maintenance
+	lastWindowID int
+	nodes        []node
+}
+
+type snoozeEntry struct {
+	snooze
+	Cancelled int64
+}
+
+type alertEntry struct {
+	App       string
+	Component string
+	AlertTime int64
+	Alerters  string
+	AlertType string
+	Resolved  int64
+}
+
+// newMemStore returns an empty in memory store with the gotel coordinator/worker reservations in place
+func newMemStore(conf Config) *memStore {
+	s := &memStore{escalations: make(map[string]escalation)}
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Unix()
+	for _, component := range []string{"coordinator", "worker"} {
+		s.lastID++
+		s.reservations = append(s.reservations, reservation{
+			JobID:       s.lastID,
+			App:         "gotel",
+			Component:   component,
+			Owner:       conf.Main.GotelOwnerEmail,
+			Notify:      conf.Main.GotelOwnerEmail,
+			Frequency:   5,
+			TimeUnits:   "minutes",
+			LastCheckin: tomorrow,
+		})
+	}
+	return s
+}
+
+// find returns the index of the reservation for app/component or -1, callers must hold the lock
+func (s *memStore) find(app, component string) int {
+	for i, res := range s.reservations {
+		if res.App == app && res.Component == component {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *memStore) StoreReservation(r *reservation) (bool, error) {
+	if err := checkFrequency(r); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(r.App, r.Component); i >= 0 {
+		res := &s.reservations[i]
+		res.Notify = r.Notify
+		res.AlertMessage = r.AlertMessage
+		res.Frequency = r.Frequency
+		res.TimeUnits = r.TimeUnits
+		res.Schedule = r.Schedule
+		res.Timezone = r.Timezone
+		res.Grace = r.Grace
+		res.MaxRuntime = r.MaxRuntime
+		res.Alerters = r.Alerters
+		res.Escalation = r.Escalation
+		res.Tags = r.Tags
+		res.Severity = r.Severity
+		res.EmailSubject = r.EmailSubject
+		return true, nil
+	}
+
+	// give new reservations one day to bake, same as the SQL stores
+	s.lastID++
+	s.reservations = append(s.reservations, reservation{
+		JobID:        s.lastID,
+		App:          r.App,
+		Component:    r.Component,
+		Owner:        r.Owner,
+		Notify:       r.Notify,
+		AlertMessage: r.AlertMessage,
+		Frequency:    r.Frequency,
+		TimeUnits:    r.TimeUnits,
+		Schedule:     r.Schedule,
+		Timezone:     r.Timezone,
+		Grace:        r.Grace,
+		MaxRuntime:   r.MaxRuntime,
+		Alerters:     r.Alerters,
+		Escalation:   r.Escalation,
+		Tags:         r.Tags,
+		Severity:     r.Severity,
+		EmailSubject: r.EmailSubject,
+		LastCheckin:  time.Now().Add(24 * time.Hour).UTC().Unix(),
+	})
+	return true, nil
+}
+
+func (s *memStore) ListReservations() ([]reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reservations := make([]reservation, len(s.reservations))
+	copy(reservations, s.reservations)
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].LastCheckin > reservations[j].LastCheckin
+	})
+	return reservations, nil
+}
+
+func (s *memStore) GetReservation(app, component string) (reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(app, component)
+	if i < 0 {
+		return reservation{}, fmt.Errorf("no reservation for [%s/%s]", app, component)
+	}
+	return s.reservations[i], nil
+}
+
+func (s *memStore) StoreCheckin(c checkin, now int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(c.App, c.Component); i >= 0 {
+		s.reservations[i].LastCheckin = now
+		s.reservations[i].NumCheckins++
+		s.reservations[i].RunStarted = 0
+		s.reservations[i].LastStatus = checkinSuccess
+		s.reservations[i].LastExitCode = c.ExitCode
+		s.reservations[i].LastOutput = c.Output
+	}
+	return true, nil
+}
+
+func (s *memStore) StoreFailure(c checkin, now int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(c.App, c.Component); i >= 0 {
+		s.reservations[i].RunStarted = 0
+		s.reservations[i].LastStatus = checkinFail
+		s.reservations[i].LastExitCode = c.ExitCode
+		s.reservations[i].LastOutput = c.Output
+	}
+	return true, nil
+}
+
+func (s *memStore) StoreStart(c checkin, now int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.find(c.App, c.Component)
+	if i < 0 {
+		return false, nil
+	}
+	s.reservations[i].RunStarted = now
+	return true, nil
+}
+
+func (s *memStore) LogHouseKeeping(c checkin, now int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := houseKeepingEntry{
+		App:         c.App,
+		Component:   c.Component,
+		Notes:       c.Notes,
+		LastCheckin: now,
+		Status:      c.Status,
+		ExitCode:    c.ExitCode,
+		Output:      c.Output,
+	}
+	if entry.Status == "" {
+		entry.Status = checkinSuccess
+	}
+	if c.Started > 0 {
+		entry.Started = c.Started
+		entry.Runtime = now - c.Started
+	}
+	s.houseKeeping = append(s.houseKeeping, entry)
+	return true, nil
+}
+
+func (s *memStore) ListHouseKeeping(app, component string, limit int) ([]houseKeepingEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := []houseKeepingEntry{}
+	for i := len(s.houseKeeping) - 1; i >= 0 && len(entries) < limit; i-- {
+		if h := s.houseKeeping[i]; h.App == app && h.Component == component {
+			entries = append(entries, h)
+		}
+	}
+	return entries, nil
+}
+
+func (s *memStore) ListRuntimes(app, component string, since int64) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runtimes := []int64{}
+	for i := len(s.houseKeeping) - 1; i >= 0; i-- {
+		h := s.houseKeeping[i]
+		if h.App == app && h.Component == component && h.Started > 0 && h.LastCheckin >= since &&
+			h.Status == checkinSuccess {
+			runtimes = append(runtimes, h.Runtime)
+		}
+	}
+	return runtimes, nil
+}
+
+func (s *memStore) StoreAck(a *ack, now int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(a.App, a.Component); i >= 0 {
+		s.reservations[i].AckedBy = a.AckedBy
+		s.reservations[i].AckedTimestamp = now
+	}
+	return true, nil
+}
+
+func (s *memStore) ClearAck(app, component string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(app, component); i >= 0 {
+		s.reservations[i].AckedBy = ""
+		s.reservations[i].AckedTimestamp = 0
+	}
+	return nil
+}
+
+func (s *memStore) StoreCheckOut(c *checkOut) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.find(c.App, c.Component); i >= 0 {
+		s.reservations = append(s.reservations[:i], s.reservations[i+1:]...)
+	}
+	return true, nil
+}
+
+func (s *memStore) StoreSnooze(p *snooze) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSnoozeID++
+	p.ID = s.lastSnoozeID
+	s.snoozes = append(s.snoozes, snoozeEntry{snooze: *p})
+	return true, nil
+}
+
+func (s *memStore) ListSnoozes(since int64) ([]snooze, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snoozes := []snooze{}
+	for _, p := range s.snoozes {
+		if p.End >= since && p.Cancelled == 0 {
+			snoozes = append(snoozes, p.snooze)
+		}
+	}
+	sort.SliceStable(snoozes, func(i, j int) bool { return snoozes[i].Start < snoozes[j].Start })
+	return snoozes, nil
+}
+
+func (s *memStore) CancelSnooze(id int, now int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range s.snoozes {
+		if p.ID == id && p.End > now && p.Cancelled == 0 {
+			s.snoozes[i].Cancelled = now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memStore) StoreAlert(res reservation, alerters []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alertEntry{
+		App:       res.App,
+		Component: res.Component,
+		AlertTime: time.Now().UTC().Unix(),
+		Alerters:  strings.Join(alerters, ","),
+		AlertType: res.AlertType,
+	})
+	if i := s.find(res.App, res.Component); i >= 0 {
+		s.reservations[i].LastAlert = s.alerts[len(s.alerts)-1].AlertTime
+	}
+	return nil
+}
+
+// firing says whether an alert entry is an unresolved alert of the reservation's alert type
+func (a alertEntry) firing(res reservation) bool {
+	return a.App == res.App && a.Component == res.Component && a.AlertType == res.AlertType && a.Resolved == 0
+}
+
+func (s *memStore) LastAlertTime(res reservation, alerterName string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var last int64
+	for _, a := range s.alerts {
+		if a.firing(res) && a.Alerters == alerterName && a.AlertTime > last {
+			last = a.AlertTime
+		}
+	}
+	return last, nil
+}
+
+func (s *memStore) FiringAlerters(res reservation) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]bool)
+	alerters := []string{}
+	for _, a := range s.alerts {
+		if a.firing(res) && !seen[a.Alerters] {
+			seen[a.Alerters] = true
+			alerters = append(alerters, a.Alerters)
+		}
+	}
+	return alerters, nil
+}
+
+func (s *memStore) ResolveAlert(res reservation, alerterName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC().Unix()
+	for i, a := range s.alerts {
+		if a.firing(res) && a.Alerters == alerterName {
+			s.alerts[i].Resolved = now
+		}
+	}
+	return nil
+}
+
+func (s *memStore) GetEscalation(res reservation) (escalation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.escalations[res.mapKey("")], nil
+}
+
+func (s *memStore) StoreEscalation(res reservation, e escalation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.escalations[res.mapKey("")] = e
+	return nil
+}
+
+func (s *memStore) ClearEscalation(res reservation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.escalations, res.mapKey(""))
+	return nil
+}
+
+func (s *memStore) StoreMaintenance(m *maintenance) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastWindowID++
+	m.ID = s.lastWindowID
+	s.maintenance = append(s.maintenance, *m)
+	return true, nil
+}
+
+func (s *memStore) ListMaintenance() ([]maintenance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	windows := make([]maintenance, len(s.maintenance))
+	copy(windows, s.maintenance)
+	return windows, nil
+}
+
+func (s *memStore) RemoveMaintenance(id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.maintenance {
+		if m.ID == id {
+			s.maintenance = append(s.maintenance[:i], s.maintenance[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memStore) ListBadGuests() ([]badGuest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[[2]string]int64)
+	guests := []badGuest{}
+	for _, a := range s.alerts {
+		key := [2]string{a.App, a.Component}
+		if _, ok := counts[key]; !ok {
+			guests = append(guests, badGuest{App: a.App, Component: a.Component})
+		}
+		counts[key]++
+	}
+	for i := range guests {
+		guests[i].NumFails = counts[[2]string{guests[i].App, guests[i].Component}]
+	}
+	sort.SliceStable(guests, func(i, j int) bool {
+		return guests[i].NumFails > guests[j].NumFails
+	})
+	return guests, nil
+}
+
+func (s *memStore) ListNodes() ([]node, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nodes := make([]node, len(s.nodes))
+	copy(nodes, s.nodes)
+	return nodes, nil
+}
+
+func (s *memStore) InsertNode(ipAddress string, nodeID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.nodes {
+		if n.IPAddress == ipAddress {
+			l.info("[%s] has already registered as a node", ipAddress)
+			return nil
+		}
+	}
+	s.nodes = append(s.nodes, node{ID: len(s.nodes) + 1, IPAddress: ipAddress, NodeID: nodeID})
+	return nil
+}
+
+func (s *memStore) RemoveNode(ipAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, n := range s.nodes {
+		if n.IPAddress == ipAddress {
+			s.nodes = append(s.nodes[:i], s.nodes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// HasLock always succeeds, an in memory store is never shared with another GoTel
+func (s *memStore) HasLock() bool {
+	return true
+}
+
+func (s *memStore) ReleaseLock() (bool, error) {
+	return true, nil
+}
+
+func (s *memStore) CleanUp(before int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	houseKeeping := s.houseKeeping[:0]
+	for _, h := range s.houseKeeping {
+		if h.LastCheckin >= before {
+			houseKeeping = append(houseKeeping, h)
+		}
+	}
+	s.houseKeeping = houseKeeping
+
+	// open alerts of reservations that are still failing are kept so they can be resolved
+	alerts := s.alerts[:0]
+	for _, a := range s.alerts {
+		open := a.Resolved == 0 && a.AlertType != "" && s.find(a.App, a.Component) >= 0
+		if a.AlertTime >= before || open {
+			alerts = append(alerts, a)
+		}
+	}
+	s.alerts = alerts
+
+	snoozes := s.snoozes[:0]
+	for _, p := range s.snoozes {
+		if p.End >= before {
+			snoozes = append(snoozes, p)
+		}
+	}
+	s.snoozes = snoozes
+
+	// escalations are kept while their reservation exists and has an open alert
+	openAlerts := make(map[string]bool)
+	for _, a := range s.alerts {
+		if a.Resolved == 0 && s.find(a.App, a.Component) >= 0 {
+			res := reservation{App: a.App, Component: a.Component, AlertType: a.AlertType}
+			openAlerts[res.mapKey("")] = true
+		}
+	}
+	for key, e := range s.escalations {
+		if e.Started < before && !openAlerts[key] {
+			delete(s.escalations, key)
+		}
+	}
+	return nil
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package gotel
+
+import (
+	"testing"
+)
+
+func Test_memStoreReservationUpsert(t *testing.T) {
+	store := newMemStore(Config{})
+
+	first := &reservation{App: "jimtest", Component: "monitor", Owner: "jim@example.com", Notify: "jim@example.com",
+		Frequency: 5, TimeUnits: "minutes"}
+	if _, err := store.StoreReservation(first); err != nil {
+		t.Fatalf("Unable to store reservation [%v]", err)
+	}
+	if _, err := store.StoreCheckin(checkin{App: "jimtest", Component: "monitor"}, 1000); err != nil {
+		t.Fatalf("Unable to store checkin [%v]", err)
+	}
+
+	second := &reservation{App: "jimtest", Component: "monitor", Owner: "bob@example.com", Notify: "ops@example.com",
+		Frequency: 10, TimeUnits: "hours"}
+	if _, err := store.StoreReservation(second); err != nil {
+		t.Fatalf("Unable to store reservation [%v]", err)
+	}
+
+	reservations, _ := store.ListReservations()
+	if len(reservations) != 3 {
+		t.Fatalf("Expected the gotel reservations plus one, got %d", len(reservations))
+	}
+
+	res, err := store.GetReservation("jimtest", "monitor")
+	if err != nil {
+		t.Fatalf("Unable to get reservation [%v]", err)
+	}
+	if res.Owner != "jim@example.com" {
+		t.Fatalf("Owner should not change on update, got [%s]", res.Owner)
+	}
+	if res.Notify != "ops@example.com" || res.Frequency != 10 || res.TimeUnits != "hours" {
+		t.Fatalf("Reservation was not updated [%v]", res)
+	}
+	if res.NumCheckins != 1 || res.LastCheckin != 1000 {
+		t.Fatalf("Checkins should survive an update [%v]", res)
+	}
+}
+
+func Test_memStoreNumCheckins(t *testing.T) {
+	store := newMemStore(Config{})
+	for i := 1; i <= 3; i++ {
+		if _, err := store.StoreCheckin(checkin{App: "gotel", Component: "worker"}, int64(i)); err != nil {
+			t.Fatalf("Unable to store checkin [%v]", err)
+		}
+	}
+	res, _ := store.GetReservation("gotel", "worker")
+	if res.NumCheckins != 3 || res.LastCheckin != 3 {
+		t.Fatalf("Expected 3 checkins with the last at 3, got [%v]", res)
+	}
+
+	// checkins for unknown reservations are dropped just like an UPDATE that matches nothing
+	if _, err := store.StoreCheckin(checkin{App: "nope", Component: "nope"}, 4); err != nil {
+		t.Fatalf("Unable to store checkin [%v]", err)
+	}
+	if _, err := store.GetReservation("nope", "nope"); err == nil {
+		t.Fatalf("Should not have created a reservation on checkin")
+	}
+}
+
+func Test_memStoreShortFrequency(t *testing.T) {
+	store := newMemStore(Config{})
+	_, err := store.StoreReservation(&reservation{App: "jimtest", Component: "monitor", Frequency: 5, TimeUnits: "seconds"})
+	if err == nil {
+		t.Fatalf("Should not store reservations under 10 seconds")
+	}
+}
@@ -0,0 +1,91 @@
+package gotel
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// mysqlDialect runs GoTel against a MySQL "gotel" database and uses GET_LOCK for coordinator election
+type mysqlDialect struct{}
+
+func (*mysqlDialect) open(dbc DbConfig) (*sql.DB, error) {
+	// clientFoundRows makes RowsAffected count the rows an UPDATE matched, like the other drivers, rather than only
+	// the ones it changed
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:3306)/gotel?clientFoundRows=true", dbc.User, dbc.Pass,
+		dbc.Host))
+	if err != nil {
+		return nil, err
+	}
+	err = db.Ping()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to ping the DB at host [%s] user [%s]: %v", dbc.Host, dbc.User, err)
+	}
+	return db, nil
+}
+
+func (*mysqlDialect) rebind(query string) string {
+	return query
+}
+
+func (*mysqlDialect) upsert(table string, columns, keys, update []string) string {
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s=VALUES(%s)", col, col)
+	}
+	return insertInto(table, columns) + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (*mysqlDialect) insertIgnore(table string, columns []string) string {
+	return strings.Replace(insertInto(table, columns), "INSERT INTO", "INSERT IGNORE INTO", 1)
+}
+
+func (*mysqlDialect) insertID(db *sql.DB, query string, args ...interface{}) (int64, error) {
+	return lastInsertID(db, query, args...)
+}
+
+func (*mysqlDialect) tablesQuery() string {
+	return `SELECT table_name FROM information_schema.tables WHERE table_schema='gotel'`
+}
+
+func (*mysqlDialect) name() string {
+	return "mysql"
+}
+
+func (*mysqlDialect) hasLock(db *sql.DB) bool {
+	var lck int
+	query := "SELECT GET_LOCK('gotel_lock', 3) as lck"
+	rows, err := db.Query(query)
+	if err != nil {
+		l.warn("Unable to acquire lock\n")
+		return false
+	}
+	defer rows.Close()
+	for rows.Next() {
+		err := rows.Scan(&lck)
+		if err != nil {
+			l.warn("Unable to acquire locking rows\n")
+		}
+		if lck == 1 {
+			// holds a lock while the connection is alive
+			l.info("Lock Acquired")
+			return true
+		}
+		l.info("Unable to acquire coordinator lock. I must be a worker [%v]", lck)
+
+	}
+	return false
+}
+
+func (*mysqlDialect) releaseLock(db *sql.DB) (bool, error) {
+	releaseQuery := "SELECT RELEASE_LOCK('gotel_lock');"
+	rows, err := db.Query(releaseQuery)
+	if err != nil {
+		l.warn("Unable to release lock\n")
+		return false, errors.New("Unable to release lock")
+	}
+	defer rows.Close()
+	return true, nil
+
+}
@@ -0,0 +1,124 @@
+package gotel
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pgLockKey is the advisory lock key used for coordinator election, "gotel" in hex
+const pgLockKey = 0x676f74656c
+
+// postgresDialect runs GoTel against a PostgreSQL "gotel" database and uses advisory locks for coordinator election
+type postgresDialect struct {
+	// advisory locks belong to a session so the connection that took the lock has to be the one to release it
+	lockConn *sql.Conn
+}
+
+func (*postgresDialect) open(dbc DbConfig) (*sql.DB, error) {
+	// sslmode and friends are picked up from the usual PG* environment variables
+	dsn := url.URL{Scheme: "postgres", User: url.UserPassword(dbc.User, dbc.Pass), Host: dbc.Host + ":5432", Path: "/gotel"}
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	err = db.Ping()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to ping the DB at host [%s] user [%s]: %v", dbc.Host, dbc.User, err)
+	}
+	return db, nil
+}
+
+// rebind turns ? placeholders into $1, $2, ...
+func (*postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+func (*postgresDialect) upsert(table string, columns, keys, update []string) string {
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s=EXCLUDED.%s", col, col)
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", insertInto(table, columns), strings.Join(keys, ", "),
+		strings.Join(sets, ", "))
+}
+
+func (*postgresDialect) insertIgnore(table string, columns []string) string {
+	return insertInto(table, columns) + " ON CONFLICT DO NOTHING"
+}
+
+// insertID asks for the id back with RETURNING, lib/pq has no LastInsertId
+func (d *postgresDialect) insertID(db *sql.DB, query string, args ...interface{}) (int64, error) {
+	var id int64
+	err := db.QueryRow(d.rebind(query)+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+func (*postgresDialect) tablesQuery() string {
+	return `SELECT table_name FROM information_schema.tables WHERE table_schema=current_schema()`
+}
+
+func (*postgresDialect) name() string {
+	return "postgres"
+}
+
+// hasLock waits up to 3 seconds for the advisory lock, the same as the GET_LOCK timeout on MySQL
+func (d *postgresDialect) hasLock(db *sql.DB) bool {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		l.warn("Unable to acquire lock\n")
+		return false
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		var lck bool
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", pgLockKey).Scan(&lck)
+		if err != nil {
+			l.warn("Unable to acquire locking rows\n")
+			break
+		}
+		if lck {
+			// holds the lock while this session is alive
+			l.info("Lock Acquired")
+			d.lockConn = conn
+			return true
+		}
+		if time.Now().After(deadline) {
+			l.info("Unable to acquire coordinator lock. I must be a worker")
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	conn.Close()
+	return false
+}
+
+func (d *postgresDialect) releaseLock(db *sql.DB) (bool, error) {
+	if d.lockConn == nil {
+		return true, nil
+	}
+	conn := d.lockConn
+	d.lockConn = nil
+	defer conn.Close()
+	_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", pgLockKey)
+	if err != nil {
+		l.warn("Unable to release lock\n")
+		return false, fmt.Errorf("Unable to release lock [%v]", err)
+	}
+	return true, nil
+}
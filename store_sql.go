@@ -0,0 +1,714 @@
+package gotel
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sqlStore is a Store backed by one of the database/sql drivers GoTel supports, the SQL that differs between
+// them lives in its dialect
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// dialect holds the SQL that differs between the databases GoTel can run against
+type dialect interface {
+	// open connects to and pings the database
+	open(dbc DbConfig) (*sql.DB, error)
+	// rebind rewrites ? placeholders into the form the driver expects
+	rebind(query string) string
+	// upsert builds an insert that updates the update columns when a row with the same keys already exists
+	upsert(table string, columns, keys, update []string) string
+	// insertIgnore builds an insert that does nothing when the row already exists
+	insertIgnore(table string, columns []string) string
+	// insertID runs an insert into a table with an id column and returns the new row's id
+	insertID(db *sql.DB, query string, args ...interface{}) (int64, error)
+	// tablesQuery lists the names of the tables in the GoTel database
+	tablesQuery() string
+	// name is the driver name, migrations use it to pick dialect specific statements
+	name() string
+	// hasLock tries to acquire the coordinator lock
+	hasLock(db *sql.DB) bool
+	// releaseLock releases the coordinator lock
+	releaseLock(db *sql.DB) (bool, error)
+}
+
+// insertInto builds a plain insert statement for the columns
+func insertInto(table string, columns []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+}
+
+// lastInsertID runs an insert and returns the id the driver reports for it, for drivers that support LastInsertId
+func lastInsertID(db *sql.DB, query string, args ...interface{}) (int64, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// prepare creates a prepared statement with the placeholders rewritten for the dialect
+func (s *sqlStore) prepare(query string) (*sql.Stmt, error) {
+	return s.db.Prepare(s.dialect.rebind(query))
+}
+
+// query runs a query with the placeholders rewritten for the dialect
+func (s *sqlStore) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStore) StoreReservation(r *reservation) (bool, error) {
+
+	// get current unix time one day into the future as the initial insert data, give it one day to bake
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Unix()
+	now := time.Now().UTC().Unix()
+
+	if err := checkFrequency(r); err != nil {
+		return false, err
+	}
+
+	stmt, err := s.prepare(s.dialect.upsert("reservations",
+		[]string{"app", "component", "owner", "notify", "alert_msg", "frequency", "time_units", "schedule", "timezone",
+			"grace_seconds", "max_runtime_seconds", "alerters", "escalation", "tags", "severity",
+			"email_subject", "inserted_timestamp", "last_checkin_timestamp"},
+		[]string{"app", "component"},
+		[]string{"notify", "alert_msg", "frequency", "time_units", "schedule", "timezone", "grace_seconds",
+			"max_runtime_seconds", "alerters", "escalation", "tags", "severity", "email_subject"}))
+
+	if err != nil {
+		l.warn("unable to prepare statement %s", err)
+		return false, errors.New("Unable to save record")
+	}
+	defer stmt.Close()
+
+	// routes are kept as JSON, NULL when the reservation uses every alerter
+	var alerters sql.NullString
+	if len(r.Alerters) > 0 {
+		routes, err := json.Marshal(r.Alerters)
+		if err != nil {
+			return false, errors.New("Unable to save record")
+		}
+		alerters = sql.NullString{String: string(routes), Valid: true}
+	}
+	var tags sql.NullString
+	if len(r.Tags) > 0 {
+		encoded, err := json.Marshal(r.Tags)
+		if err != nil {
+			return false, errors.New("Unable to save record")
+		}
+		tags = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	res, err := stmt.Exec(r.App, r.Component, r.Owner, r.Notify, r.AlertMessage, r.Frequency, r.TimeUnits, r.Schedule,
+		r.Timezone, r.Grace.seconds(), r.MaxRuntime.seconds(), alerters, r.Escalation, tags, r.Severity,
+		r.EmailSubject, now, tomorrow)
+	if err != nil {
+		l.warn("Unable to insert record %s", err)
+		return false, errors.New("Unable to save record")
+	}
+
+	rowCnt, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.New("Unable to save record")
+	}
+
+	l.info("Insertedaffected = %d\n", rowCnt)
+	return true, nil
+
+}
+
+func (s *sqlStore) LogHouseKeeping(c checkin, now int64) (bool, error) {
+
+	//Insert
+	stmt, err := s.prepare(insertInto("housekeeping", []string{"app", "component", "notes", "last_checkin_timestamp",
+		"started_timestamp", "runtime_seconds", "status", "exit_code", "output"}))
+	if err != nil {
+		l.warn("Unable to prepare record %s", err)
+		return false, errors.New("Unable to store checkin")
+	}
+	defer stmt.Close()
+	var started, runtime sql.NullInt64
+	if c.Started > 0 {
+		started = sql.NullInt64{Int64: c.Started, Valid: true}
+		runtime = sql.NullInt64{Int64: now - c.Started, Valid: true}
+	}
+	status := c.Status
+	if status == "" {
+		status = checkinSuccess
+	}
+	_, err = stmt.Exec(c.App, c.Component, c.Notes, now, started, runtime, status, c.ExitCode, c.Output)
+	if err != nil {
+		l.warn("Unable to insert record %s", err)
+		return false, errors.New("Unable to store checkin")
+	}
+
+	return true, nil
+}
+
+func (s *sqlStore) ListHouseKeeping(app, component string, limit int) ([]houseKeepingEntry, error) {
+	rows, err := s.query(`SELECT notes, last_checkin_timestamp, started_timestamp, runtime_seconds, status, exit_code, output
+		FROM housekeeping WHERE app=? AND component=? ORDER BY last_checkin_timestamp DESC, id DESC LIMIT ?`,
+		app, component, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []houseKeepingEntry{}
+	for rows.Next() {
+		var (
+			notes, status, output      sql.NullString
+			started, runtime, exitCode sql.NullInt64
+		)
+		h := houseKeepingEntry{App: app, Component: component}
+		if err = rows.Scan(&notes, &h.LastCheckin, &started, &runtime, &status, &exitCode, &output); err != nil {
+			return nil, err
+		}
+		h.Notes = notes.String
+		h.Started = started.Int64
+		h.Runtime = runtime.Int64
+		h.Status = status.String
+		h.ExitCode = int(exitCode.Int64)
+		h.Output = output.String
+		entries = append(entries, h)
+	}
+	return entries, rows.Err()
+}
+
+func (s *sqlStore) ListRuntimes(app, component string, since int64) ([]int64, error) {
+	rows, err := s.query(`SELECT runtime_seconds FROM housekeeping
+		WHERE app=? AND component=? AND runtime_seconds IS NOT NULL AND last_checkin_timestamp >= ?
+		AND (status IS NULL OR status = ?)
+		ORDER BY last_checkin_timestamp DESC`, app, component, since, checkinSuccess)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	runtimes := []int64{}
+	for rows.Next() {
+		var runtime int64
+		if err = rows.Scan(&runtime); err != nil {
+			return nil, err
+		}
+		runtimes = append(runtimes, runtime)
+	}
+	return runtimes, rows.Err()
+}
+
+func (s *sqlStore) StoreCheckin(c checkin, now int64) (bool, error) {
+
+	stmt, err := s.prepare(`UPDATE reservations SET last_checkin_timestamp = ?, num_checkins = num_checkins + 1, run_started_timestamp = NULL,
+		last_status = ?, last_exit_code = ?, last_output = ? WHERE app=? AND component=?`)
+	if err != nil {
+		l.warn("Unable to prepare record %s", err)
+		return false, errors.New("Unable to prepare checkin")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(now, checkinSuccess, c.ExitCode, c.Output, c.App, c.Component)
+	if err != nil {
+		l.warn("Unable to update reservation %s", err)
+		return false, errors.New("Unable to store checkin")
+	}
+
+	return true, nil
+}
+
+func (s *sqlStore) StoreFailure(c checkin, now int64) (bool, error) {
+
+	stmt, err := s.prepare(`UPDATE reservations SET run_started_timestamp = NULL, last_status = ?, last_exit_code = ?, last_output = ?
+		WHERE app=? AND component=?`)
+	if err != nil {
+		l.warn("Unable to prepare record %s", err)
+		return false, errors.New("Unable to prepare failure")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(checkinFail, c.ExitCode, c.Output, c.App, c.Component)
+	if err != nil {
+		l.warn("Unable to update reservation %s", err)
+		return false, errors.New("Unable to store failure")
+	}
+
+	return true, nil
+}
+
+func (s *sqlStore) StoreStart(c checkin, now int64) (bool, error) {
+
+	stmt, err := s.prepare("UPDATE reservations SET run_started_timestamp = ? WHERE app=? AND component=?")
+	if err != nil {
+		l.warn("Unable to prepare record %s", err)
+		return false, errors.New("Unable to prepare start")
+	}
+	defer stmt.Close()
+	result, err := stmt.Exec(now, c.App, c.Component)
+	if err != nil {
+		l.warn("Unable to update reservation %s", err)
+		return false, errors.New("Unable to store start")
+	}
+	rowCnt, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New("Unable to store start")
+	}
+
+	return rowCnt > 0, nil
+}
+
+func (s *sqlStore) StoreCheckOut(c *checkOut) (bool, error) {
+
+	stmt, err := s.prepare("DELETE FROM reservations WHERE app=? AND component=?")
+	if err != nil {
+		l.warn("Unable to prepare record %s", err)
+		return false, errors.New("Unable to prepare checkin")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(c.App, c.Component)
+	if err != nil {
+		l.warn("Unable to update reservation %s", err)
+		return false, errors.New("Unable to store checkin")
+	}
+	return true, nil
+}
+
+func (s *sqlStore) StoreAck(a *ack, now int64) (bool, error) {
+
+	stmt, err := s.prepare("UPDATE reservations SET acked_by = ?, acked_timestamp = ? WHERE app=? AND component=?")
+	if err != nil {
+		l.warn("Unable to prepare record %s", err)
+		return false, errors.New("Unable to prepare ack")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(a.AckedBy, now, a.App, a.Component)
+	if err != nil {
+		l.warn("Unable to update reservation %s", err)
+		return false, errors.New("Unable to store ack")
+	}
+	return true, nil
+}
+
+func (s *sqlStore) ClearAck(app, component string) error {
+	_, err := s.db.Exec(s.dialect.rebind("UPDATE reservations SET acked_by = NULL, acked_timestamp = NULL WHERE app=? AND component=?"),
+		app, component)
+	if err != nil {
+		l.warn("Unable to clear ack %s", err)
+		return errors.New("Unable to clear ack")
+	}
+	return nil
+}
+
+func (s *sqlStore) StoreSnooze(p *snooze) (bool, error) {
+
+	id, err := s.dialect.insertID(s.db, insertInto("snoozes", []string{"app", "component", "start_timestamp",
+		"end_timestamp", "reason", "created_by"}), p.App, p.Component, p.Start, p.End, p.Reason, p.CreatedBy)
+	if err != nil {
+		l.warn("Unable to insert snooze %s", err)
+		return false, errors.New("Unable to store snooze")
+	}
+	p.ID = int(id)
+
+	return true, nil
+}
+
+func (s *sqlStore) ListSnoozes(since int64) ([]snooze, error) {
+	rows, err := s.query(`SELECT id, app, component, start_timestamp, end_timestamp, reason, created_by FROM snoozes
+		WHERE end_timestamp >= ? AND cancelled_timestamp IS NULL ORDER BY start_timestamp`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snoozes := []snooze{}
+	for rows.Next() {
+		var reason, createdBy sql.NullString
+		p := snooze{}
+		if err = rows.Scan(&p.ID, &p.App, &p.Component, &p.Start, &p.End, &reason, &createdBy); err != nil {
+			return nil, err
+		}
+		p.Reason = reason.String
+		p.CreatedBy = createdBy.String
+		snoozes = append(snoozes, p)
+	}
+	return snoozes, rows.Err()
+}
+
+func (s *sqlStore) CancelSnooze(id int, now int64) (bool, error) {
+	result, err := s.db.Exec(s.dialect.rebind(`UPDATE snoozes SET cancelled_timestamp = ?
+		WHERE id=? AND end_timestamp > ? AND cancelled_timestamp IS NULL`), now, id, now)
+	if err != nil {
+		l.warn("Unable to cancel snooze %s", err)
+		return false, errors.New("Unable to cancel snooze")
+	}
+	rowCnt, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New("Unable to cancel snooze")
+	}
+	return rowCnt > 0, nil
+}
+
+const reservationColumns = "id, app, component, owner, notify, alert_msg, frequency, time_units, schedule, timezone, " +
+	"grace_seconds, max_runtime_seconds, run_started_timestamp, last_checkin_timestamp, num_checkins, last_status, " +
+	"last_exit_code, last_output, last_alert_timestamp, alerters, escalation, acked_by, acked_timestamp, tags, " +
+	"severity, email_subject"
+
+func scanReservation(rows *sql.Rows) (reservation, error) {
+	var (
+		alertMessage, schedule, timezone, lastStatus, lastOutput, alerters, escalation, ackedBy, tags, severity, emailSubject sql.NullString
+		grace, maxRuntime, runStarted, lastExitCode, lastAlert, acked                                                         sql.NullInt64
+	)
+	res := reservation{}
+	err := rows.Scan(&res.JobID, &res.App, &res.Component, &res.Owner, &res.Notify, &alertMessage, &res.Frequency,
+		&res.TimeUnits, &schedule, &timezone, &grace, &maxRuntime, &runStarted, &res.LastCheckin, &res.NumCheckins,
+		&lastStatus, &lastExitCode, &lastOutput, &lastAlert, &alerters, &escalation, &ackedBy, &acked, &tags,
+		&severity, &emailSubject)
+	if err != nil {
+		return res, err
+	}
+	res.Grace = duration(time.Duration(grace.Int64) * time.Second)
+	res.MaxRuntime = duration(time.Duration(maxRuntime.Int64) * time.Second)
+	res.RunStarted = runStarted.Int64
+	res.AlertMessage = alertMessage.String
+	res.Schedule = schedule.String
+	res.Timezone = timezone.String
+	res.LastStatus = lastStatus.String
+	res.LastExitCode = int(lastExitCode.Int64)
+	res.LastOutput = lastOutput.String
+	res.LastAlert = lastAlert.Int64
+	res.Escalation = escalation.String
+	res.Severity = severity.String
+	res.EmailSubject = emailSubject.String
+	res.AckedBy = ackedBy.String
+	res.AckedTimestamp = acked.Int64
+	if alerters.String != "" {
+		if err = json.Unmarshal([]byte(alerters.String), &res.Alerters); err != nil {
+			return res, fmt.Errorf("invalid alerters for [%s/%s] [%v]", res.App, res.Component, err)
+		}
+	}
+	if tags.String != "" {
+		if err = json.Unmarshal([]byte(tags.String), &res.Tags); err != nil {
+			return res, fmt.Errorf("invalid tags for [%s/%s] [%v]", res.App, res.Component, err)
+		}
+	}
+	return res, nil
+}
+
+func (s *sqlStore) ListReservations() ([]reservation, error) {
+	rows, err := s.query("SELECT " + reservationColumns + " FROM reservations ORDER BY last_checkin_timestamp DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reservations := []reservation{}
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, res)
+	}
+	return reservations, rows.Err()
+}
+
+func (s *sqlStore) GetReservation(app, component string) (reservation, error) {
+	rows, err := s.query("SELECT "+reservationColumns+" FROM reservations WHERE app=? AND component=?", app, component)
+	if err != nil {
+		return reservation{}, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return reservation{}, err
+		}
+		return reservation{}, fmt.Errorf("no reservation for [%s/%s]", app, component)
+	}
+	return scanReservation(rows)
+}
+
+func (s *sqlStore) StoreAlert(res reservation, alerters []string) error {
+	now := time.Now().UTC().Unix()
+	altertNames := strings.Join(alerters, ",")
+	stmt, err := s.prepare("INSERT INTO alerts(app, component, alert_time, alerters, alert_type) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		l.warn("Unable to prepare storealert record %s", err)
+		return errors.New("Unable to prepare alert")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(res.App, res.Component, now, altertNames, res.AlertType)
+	if err != nil {
+		l.warn("Unable to insert alert record %s", err)
+		return errors.New("Unable to store alert")
+	}
+
+	_, err = s.db.Exec(s.dialect.rebind("UPDATE reservations SET last_alert_timestamp = ? WHERE app=? AND component=?"),
+		now, res.App, res.Component)
+	if err != nil {
+		l.warn("Unable to update last alert time %s", err)
+		return errors.New("Unable to store alert")
+	}
+	return nil
+}
+
+func (s *sqlStore) LastAlertTime(res reservation, alerterName string) (int64, error) {
+	var last sql.NullInt64
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT MAX(alert_time) FROM alerts
+		WHERE app=? AND component=? AND alert_type=? AND alerters=? AND resolved_time IS NULL`),
+		res.App, res.Component, res.AlertType, alerterName).Scan(&last)
+	if err != nil {
+		return 0, err
+	}
+	return last.Int64, nil
+}
+
+func (s *sqlStore) FiringAlerters(res reservation) ([]string, error) {
+	rows, err := s.query(`SELECT DISTINCT alerters FROM alerts
+		WHERE app=? AND component=? AND alert_type=? AND resolved_time IS NULL`, res.App, res.Component, res.AlertType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	alerters := []string{}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		alerters = append(alerters, name)
+	}
+	return alerters, rows.Err()
+}
+
+func (s *sqlStore) ResolveAlert(res reservation, alerterName string) error {
+	stmt, err := s.prepare(`UPDATE alerts SET resolved_time = ?
+		WHERE app=? AND component=? AND alert_type=? AND alerters=? AND resolved_time IS NULL`)
+	if err != nil {
+		l.warn("Unable to prepare resolve alert %s", err)
+		return errors.New("Unable to prepare resolve alert")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(time.Now().UTC().Unix(), res.App, res.Component, res.AlertType, alerterName)
+	if err != nil {
+		l.warn("Unable to resolve alert %s", err)
+		return errors.New("Unable to resolve alert")
+	}
+	return nil
+}
+
+func (s *sqlStore) GetEscalation(res reservation) (escalation, error) {
+	e := escalation{}
+	err := s.db.QueryRow(s.dialect.rebind("SELECT started_timestamp, level FROM escalations WHERE app=? AND component=? AND alert_type=?"),
+		res.App, res.Component, res.AlertType).Scan(&e.Started, &e.Level)
+	if err == sql.ErrNoRows {
+		return escalation{}, nil
+	}
+	return e, err
+}
+
+func (s *sqlStore) StoreEscalation(res reservation, e escalation) error {
+	stmt, err := s.prepare(s.dialect.upsert("escalations",
+		[]string{"app", "component", "alert_type", "started_timestamp", "level"},
+		[]string{"app", "component", "alert_type"},
+		[]string{"started_timestamp", "level"}))
+	if err != nil {
+		l.warn("Unable to prepare escalation %s", err)
+		return errors.New("Unable to prepare escalation")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(res.App, res.Component, res.AlertType, e.Started, e.Level)
+	if err != nil {
+		l.warn("Unable to store escalation %s", err)
+		return errors.New("Unable to store escalation")
+	}
+	return nil
+}
+
+func (s *sqlStore) ClearEscalation(res reservation) error {
+	_, err := s.db.Exec(s.dialect.rebind("DELETE FROM escalations WHERE app=? AND component=? AND alert_type=?"),
+		res.App, res.Component, res.AlertType)
+	if err != nil {
+		l.warn("Unable to clear escalation %s", err)
+		return errors.New("Unable to clear escalation")
+	}
+	return nil
+}
+
+func (s *sqlStore) StoreMaintenance(m *maintenance) (bool, error) {
+
+	id, err := s.dialect.insertID(s.db, insertInto("maintenance_windows", []string{"app", "component", "tag", "schedule",
+		"timezone", "duration_seconds", "reason", "created_by"}), m.App, m.Component, m.Tag, m.Schedule, m.Timezone,
+		m.Duration.seconds(), m.Reason, m.CreatedBy)
+	if err != nil {
+		l.warn("Unable to insert maintenance window %s", err)
+		return false, errors.New("Unable to store maintenance window")
+	}
+	m.ID = int(id)
+	return true, nil
+}
+
+func (s *sqlStore) ListMaintenance() ([]maintenance, error) {
+	rows, err := s.query(`SELECT id, app, component, tag, schedule, timezone, duration_seconds, reason, created_by
+		FROM maintenance_windows ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	windows := []maintenance{}
+	for rows.Next() {
+		var app, component, tag, timezone, reason, createdBy sql.NullString
+		var seconds int64
+		m := maintenance{}
+		err = rows.Scan(&m.ID, &app, &component, &tag, &m.Schedule, &timezone, &seconds, &reason, &createdBy)
+		if err != nil {
+			return nil, err
+		}
+		m.App, m.Component, m.Tag, m.Timezone = app.String, component.String, tag.String, timezone.String
+		m.Reason, m.CreatedBy = reason.String, createdBy.String
+		m.Duration = duration(time.Duration(seconds) * time.Second)
+		windows = append(windows, m)
+	}
+	return windows, rows.Err()
+}
+
+func (s *sqlStore) RemoveMaintenance(id int) (bool, error) {
+	result, err := s.db.Exec(s.dialect.rebind("DELETE FROM maintenance_windows WHERE id=?"), id)
+	if err != nil {
+		l.warn("Unable to remove maintenance window %s", err)
+		return false, errors.New("Unable to remove maintenance window")
+	}
+	rowCnt, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.New("Unable to remove maintenance window")
+	}
+	return rowCnt > 0, nil
+}
+
+func (s *sqlStore) ListBadGuests() ([]badGuest, error) {
+	query := "SELECT app, component, count(*) AS cnt FROM alerts GROUP BY app, component ORDER by cnt DESC"
+	rows, err := s.query(query)
+	if err != nil {
+		return nil, err
+	}
+	guests := []badGuest{}
+	defer rows.Close()
+	for rows.Next() {
+		res := badGuest{}
+		err = rows.Scan(&res.App, &res.Component, &res.NumFails)
+		if err != nil {
+			return nil, err
+		}
+		guests = append(guests, res)
+	}
+	return guests, rows.Err()
+}
+
+func (s *sqlStore) ListNodes() ([]node, error) {
+	rows, err := s.query("SELECT id, ip_address, node_id FROM nodes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	nodes := []node{}
+	defer rows.Close()
+	for rows.Next() {
+		n := node{}
+		err = rows.Scan(&n.ID, &n.IPAddress, &n.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+func (s *sqlStore) InsertNode(ipAddress string, nodeID int) error {
+	stmt, err := s.prepare(s.dialect.insertIgnore("nodes", []string{"ip_address", "node_id"}))
+	if err != nil {
+		l.err("Unable to prepare insertself record %s", err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.Exec(ipAddress, nodeID)
+	if err != nil {
+		l.warn("Unable to insert insertself record %s", err)
+		return err
+	}
+	if cnt, err := res.RowsAffected(); err == nil && cnt == 0 {
+		l.info("[%s] has already registered as a node", ipAddress)
+	}
+	return nil
+}
+
+func (s *sqlStore) RemoveNode(ipAddress string) error {
+	stmt, err := s.prepare("DELETE FROM nodes WHERE ip_address=?")
+	if err != nil {
+		l.warn("Unable to prepare record %s", err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(ipAddress)
+	if err != nil {
+		l.warn("Unable to run delete operation for remove node [%v]", err)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) HasLock() bool {
+	return s.dialect.hasLock(s.db)
+}
+
+func (s *sqlStore) ReleaseLock() (bool, error) {
+	return s.dialect.releaseLock(s.db)
+}
+
+func (s *sqlStore) CleanUp(before int64) error {
+
+	// clean up housekeeping
+	stmt, err := s.prepare("DELETE FROM housekeeping WHERE last_checkin_timestamp < ?")
+	if err != nil {
+		l.err("Unable to prepare cleaup housekeeping statement")
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(before)
+	if err != nil {
+		l.err("Unable cleanup old housekeeping logs, this could be bad [%v]", err)
+		return err
+	}
+
+	// clean up alerts, keeping the open ones of reservations that are still failing so they can be resolved
+	stmt, err = s.prepare(`DELETE FROM alerts WHERE alert_time < ? AND (resolved_time IS NOT NULL OR alert_type IS NULL
+		OR NOT EXISTS (SELECT 1 FROM reservations r WHERE r.app = alerts.app AND r.component = alerts.component))`)
+	if err != nil {
+		l.err("Unable to prepare cleaup alerts statement")
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(before)
+	if err != nil {
+		l.err("Unable cleanup old alerts logs, this could be bad [%v]", err)
+		return err
+	}
+
+	// clean up snooze windows that are long over
+	_, err = s.db.Exec(s.dialect.rebind("DELETE FROM snoozes WHERE end_timestamp < ?"), before)
+	if err != nil {
+		l.err("Unable cleanup old snoozes [%v]", err)
+		return err
+	}
+
+	// clean up escalations left behind by reservations that checked out while failing, or that have no open alert.
+	// a job that has been failing for longer than the logs are kept keeps its level.
+	_, err = s.db.Exec(s.dialect.rebind(`DELETE FROM escalations WHERE started_timestamp < ? AND (
+		NOT EXISTS (SELECT 1 FROM reservations r WHERE r.app = escalations.app AND r.component = escalations.component)
+		OR NOT EXISTS (SELECT 1 FROM alerts a WHERE a.app = escalations.app AND a.component = escalations.component
+			AND a.alert_type = escalations.alert_type AND a.resolved_time IS NULL))`), before)
+	if err != nil {
+		l.err("Unable cleanup old escalations [%v]", err)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,106 @@
+package gotel
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// sqliteDialect runs GoTel against a local SQLite file. There is no server to hold a GET_LOCK style lock so
+// coordinator election takes a file lock on a file next to the database, which works for every GoTel on the box.
+type sqliteDialect struct {
+	lockPath string
+	lockFile *os.File
+}
+
+func (d *sqliteDialect) open(dbc DbConfig) (*sql.DB, error) {
+	if dbc.Path == "" {
+		return nil, fmt.Errorf("A DB path is required for the sqlite3 driver")
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000", dbc.Path))
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allows one writer at a time, funnel everything through one connection rather than fight over it
+	db.SetMaxOpenConns(1)
+	err = db.Ping()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open the DB at path [%s]: %v", dbc.Path, err)
+	}
+	d.lockPath = dbc.Path + ".lock"
+	return db, nil
+}
+
+func (*sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (*sqliteDialect) upsert(table string, columns, keys, update []string) string {
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s=excluded.%s", col, col)
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", insertInto(table, columns), strings.Join(keys, ", "),
+		strings.Join(sets, ", "))
+}
+
+func (*sqliteDialect) insertIgnore(table string, columns []string) string {
+	return strings.Replace(insertInto(table, columns), "INSERT INTO", "INSERT OR IGNORE INTO", 1)
+}
+
+func (*sqliteDialect) insertID(db *sql.DB, query string, args ...interface{}) (int64, error) {
+	return lastInsertID(db, query, args...)
+}
+
+func (*sqliteDialect) tablesQuery() string {
+	return `SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'`
+}
+
+func (*sqliteDialect) name() string {
+	return "sqlite3"
+}
+
+// hasLock waits up to 3 seconds for the lock file, the same as the GET_LOCK timeout on MySQL
+func (d *sqliteDialect) hasLock(db *sql.DB) bool {
+	f, err := os.OpenFile(d.lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		l.warn("Unable to open lock file [%s] [%v]", d.lockPath, err)
+		return false
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		locked, err := tryLock(f)
+		if locked {
+			l.info("Lock Acquired")
+			d.lockFile = f
+			return true
+		}
+		if err != nil {
+			l.warn("Unable to lock [%s] [%v]", d.lockPath, err)
+			break
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	f.Close()
+	l.info("Unable to acquire coordinator lock. I must be a worker")
+	return false
+}
+
+func (d *sqliteDialect) releaseLock(db *sql.DB) (bool, error) {
+	if d.lockFile == nil {
+		return true, nil
+	}
+	// closing the file drops the lock
+	err := d.lockFile.Close()
+	d.lockFile = nil
+	if err != nil {
+		l.warn("Unable to release lock\n")
+		return false, fmt.Errorf("Unable to release lock [%v]", err)
+	}
+	return true, nil
+}
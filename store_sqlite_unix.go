@@ -0,0 +1,17 @@
+//go:build !windows
+
+package gotel
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLock takes an exclusive flock on the file without waiting, false when another process holds it
+func tryLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
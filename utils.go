@@ -1,6 +1,7 @@
 package gotel
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -52,6 +54,40 @@ var magnitudes = []struct {
 	{math.MaxInt64, "a long while %s", 1},
 }
 
+// duration is a time.Duration that reads and writes JSON as a string like "10m", plain numbers are taken as seconds
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		*d = duration(time.Duration(value) * time.Second)
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = duration(parsed)
+	case nil:
+		*d = 0
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+	return nil
+}
+
+// seconds returns the duration in whole seconds, the way it's stored in the DB
+func (d duration) seconds() int64 {
+	return int64(time.Duration(d) / time.Second)
+}
+
 // returns the external IP of the machine you're on
 func externalIP() (string, error) {
 	ifaces, err := net.Interfaces()
@@ -125,6 +161,34 @@ func RelTime(a, b time.Time, albl, blbl string) string {
 	return fmt.Sprintf(mag.format, args...)
 }
 
+// head returns the first max bytes of s without splitting a UTF-8 character
+func head(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for len(s) > 0 {
+		r, size := utf8.DecodeLastRuneInString(s)
+		if r != utf8.RuneError || size != 1 {
+			break
+		}
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// tail returns the last max bytes of s without splitting a UTF-8 character
+func tail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[len(s)-max:]
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return s
+}
+
 func (res reservation) formatAlert(format string) string {
 	lastCheckin := time.Unix(res.LastCheckin, 0)
 	ip, err := externalIP()
@@ -132,17 +196,35 @@ func (res reservation) formatAlert(format string) string {
 		ip = "N/A"
 	}
 
+	frequency := strconv.Itoa(res.Frequency) + res.TimeUnits
+	if res.Schedule != "" {
+		frequency = res.Schedule
+		if res.Timezone != "" {
+			frequency += " " + res.Timezone
+		}
+	}
+
+	started := "N/A"
+	if res.RunStarted > 0 {
+		started = RelTime(time.Unix(res.RunStarted, 0), time.Now(), "ago", "")
+	}
+
 	replacer := strings.NewReplacer(
 		"{jobid}", strconv.Itoa(res.JobID),
 		"{app}", res.App,
 		"{component}", res.Component,
 		"{owner}", res.Owner,
 		"{notify}", res.Notify,
-		"{frequency}", strconv.Itoa(res.Frequency) + res.TimeUnits,
+		"{frequency}", frequency,
 		"{last}", time.Unix(res.LastCheckin, 0).Format(time.RFC1123),
 		"{since}", RelTime(lastCheckin, time.Now(), "ago", ""),
 		"{checkins}", strconv.Itoa(res.NumCheckins),
 		"{srv}", ip,
+		"{started}", started,
+		"{max_runtime}", time.Duration(res.MaxRuntime).String(),
+		"{status}", res.LastStatus,
+		"{exitcode}", strconv.Itoa(res.LastExitCode),
+		"{output}", res.LastOutput,
 	)
 
 	return replacer.Replace(format)